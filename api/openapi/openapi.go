@@ -0,0 +1,29 @@
+// Package openapi встраивает OpenAPI-описание HTTP API сервиса заказов.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Load разбирает и проверяет встроенное описание API
+func Load() (*openapi3.T, error) {
+	const op = "openapi.Load"
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: load spec: %w", op, err)
+	}
+
+	if err = doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("%s: validate spec: %w", op, err)
+	}
+
+	return doc, nil
+}
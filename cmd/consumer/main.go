@@ -0,0 +1,9 @@
+package main
+
+import (
+	"github.com/tumbleweedd/two_services_system/order_service/internal/app"
+)
+
+func main() {
+	app.RunConsumer()
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os/signal"
+	"syscall"
+
 	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/outbox"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/outbox_producer"
 	producer "github.com/tumbleweedd/two_services_system/order_service/pkg/brokers/kafka/outbox_producer"
 	"github.com/tumbleweedd/two_services_system/order_service/pkg/databases/postgres"
 	"github.com/tumbleweedd/two_services_system/order_service/pkg/logger"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
@@ -15,7 +22,7 @@ func main() {
 
 	log := logger.SetupLogger(cfg.Env)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer cancel()
 
 	db, err := postgres.NewPostgresDB(ctx, log, postgresDSN(&cfg.Postgres))
@@ -25,13 +32,52 @@ func main() {
 
 	newProducer := producer.NewProducer(cfg.Kafka.Port, log)
 
-	outboxProducer := outbox_producer.New(newProducer, db.GetDB(), cfg.Kafka, log)
+	encoder, err := encoding.NewMessageEncoder(cfg.Kafka)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create event encoder: %v", err))
+	}
+
+	outboxProducer := outbox_producer.New(newProducer, db.GetDB(), cfg.Kafka, cfg.Outbox, encoder, log)
+
+	retention := outbox_producer.NewRetention(db.GetDB(), cfg.Outbox.Retention, log)
+
+	var wakeups <-chan struct{}
+
+	listener, err := postgres.NewListener(log, postgresDSN(&cfg.Postgres), outbox.NotifyChannel)
+	if err != nil {
+		log.Warn("failed to listen for outbox notifications, falling back to polling", slog.String("error", err.Error()))
+	} else {
+		wakeups = listener.Wakeups()
+		defer func() {
+			if closeErr := listener.Close(); closeErr != nil {
+				log.Error("failed to close postgres listener", slog.String("error", closeErr.Error()))
+			}
+		}()
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return outboxProducer.Run(groupCtx, wakeups)
+	})
+	group.Go(func() error {
+		return retention.Run(groupCtx)
+	})
+
+	log.Info("outbox relay started")
+
+	if err = group.Wait(); err != nil {
+		panic(fmt.Sprintf("outbox relay error: %v", err.Error()))
+	}
+
+	if err = newProducer.Close(); err != nil {
+		log.Error("failed to close kafka producer", slog.String("error", err.Error()))
+	}
 
-	if err = outboxProducer.ProduceMessages(ctx); err != nil {
-		panic(fmt.Sprintf("produce messages error: %v", err.Error()))
+	if err = db.Close(); err != nil {
+		panic(fmt.Sprintf("failed to close postgres: %v", err))
 	}
 
-	log.Info("messages were successfully sent to their topics")
+	log.Info("outbox relay stopped")
 }
 
 func postgresDSN(psqlCfg *config.PostgresConfig) string {
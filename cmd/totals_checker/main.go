@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/app"
+)
+
+func main() {
+	os.Exit(app.RunTotalsCheck())
+}
@@ -11,14 +11,19 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/rcrowley/go-metrics"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/app/grpc"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/app/http"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/cache_impl"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/repository"
 	orderCancellationsService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/cancel"
 	orderCreationService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/create"
 	orderRetrievalService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/get"
+	pointsService "github.com/tumbleweedd/two_services_system/order_service/internal/services/points"
+	"github.com/tumbleweedd/two_services_system/order_service/pkg/brokers/kafka/producer"
 	"github.com/tumbleweedd/two_services_system/order_service/pkg/databases/postgres"
 	"github.com/tumbleweedd/two_services_system/order_service/pkg/logger"
 )
@@ -37,17 +42,34 @@ func Run() {
 
 	cache := setupCache(log)
 
-	orderCreationSvc := orderCreationService.New(log, repo)
+	notifications := setupNotifications(log, &cfg.Kafka)
+
+	orderCreationSvc := orderCreationService.New(log, cache, repo, repo, notifications)
 	orderRetrievalSvc := orderRetrievalService.New(log, cache, repo)
-	orderCancellationsSvc := orderCancellationsService.New(log, cache, repo, repo)
+	orderCancellationsSvc := orderCancellationsService.New(log, repo, notifications)
+	pointsSvc := pointsService.New(log, repo)
 
-	httpServer := http.NewApp(
+	httpServer, err := http.NewApp(
 		log,
 		orderCreationSvc,
 		orderRetrievalSvc,
 		orderCancellationsSvc,
+		pointsSvc,
+		repo,
+		map[string]metrics.Registry{"notifications": notifications.MetricRegistry()},
 		&cfg.HTTP,
 	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create http server: %v", err))
+	}
+
+	grpcServer := grpc.NewApp(
+		log,
+		orderCreationSvc,
+		orderRetrievalSvc,
+		orderCancellationsSvc,
+		&cfg.GRPC,
+	)
 
 	go func() {
 		httpServer.RunWithPanic()
@@ -55,6 +77,19 @@ func Run() {
 
 	log.Info("http server started")
 
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		httpServer.RunIdempotencyPurge(purgeCtx)
+	}()
+
+	go func() {
+		grpcServer.RunWithPanic()
+	}()
+
+	log.Info("grpc server started")
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	<-stop
@@ -67,6 +102,26 @@ func Run() {
 
 	log.Info("http server stopped")
 
+	stopPurge()
+	<-purgeDone
+
+	log.Info("idempotency keys purge stopped")
+
+	grpcCtx, grpcCancel := context.WithTimeout(ctx, cfg.GRPC.ShutdownTimeout)
+	defer grpcCancel()
+
+	if err := grpcServer.Shutdown(grpcCtx); err != nil {
+		log.Warn("grpc server stopped forcibly", slog.String("error", err.Error()))
+	}
+
+	log.Info("grpc server stopped")
+
+	if err := notifications.Close(); err != nil {
+		panic(fmt.Sprintf("failed to close notifications producer: %v", err))
+	}
+
+	log.Info("notifications producer flushed")
+
 	if err := db.Close(); err != nil {
 		panic(fmt.Sprintf("failed to close postgres: %v", err))
 	}
@@ -89,6 +144,32 @@ func setupDatabase(ctx context.Context, log *slog.Logger, cfg *config.Config) *p
 	return postgresDB
 }
 
+// notificationPublisher - best-effort уведомления о заказах рядом с outbox
+type notificationPublisher interface {
+	PublishOrderEvent(ctx context.Context, order *models.Order)
+	PublishStatusEvent(ctx context.Context, status *models.StatusStruct)
+	MetricRegistry() metrics.Registry
+	Close() error
+}
+
+func setupNotifications(log *slog.Logger, cfg *config.KafkaConfig) notificationPublisher {
+	if !cfg.Notifications {
+		return producer.NopProducer{}
+	}
+
+	encoder, err := encoding.NewMessageEncoder(*cfg)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create event encoder: %v", err))
+	}
+
+	notifications, err := producer.NewProducer(log, encoder, cfg.OrderEventTopic, cfg.StatusEventTopic, cfg.BrokerList)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create notifications producer: %v", err))
+	}
+
+	return notifications
+}
+
 func setupCache(log *slog.Logger) *cache_impl.Cache {
 	hashicorpCache := expirable.NewLRU[uuid.UUID, *models.Order](5, nil, time.Minute*10)
 
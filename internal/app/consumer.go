@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rcrowley/go-metrics"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/consumer"
+	metricsHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/metrics"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/repository"
+	"github.com/tumbleweedd/two_services_system/order_service/pkg/logger"
+)
+
+// RunConsumer запускает обработку событий оплаты из Kafka
+func RunConsumer() {
+	cfg := config.InitConfig()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	db := setupDatabase(ctx, log, &cfg)
+
+	repo := repository.NewRepository(log, db.GetDB())
+
+	notifications := setupNotifications(log, &cfg.Kafka)
+
+	paymentHandler := consumer.NewPaymentHandler(log, repo, repo, notifications)
+
+	paymentConsumer, err := consumer.New(
+		log,
+		cfg.Kafka.BrokerList,
+		cfg.Kafka.ConsumerGroup,
+		[]string{cfg.Kafka.PaymentEventTopic},
+		paymentHandler,
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create kafka consumer: %v", err))
+	}
+
+	metricsServer := runMetricsServer(log, &cfg.Consumer, map[string]metrics.Registry{
+		"payment_consumer": paymentConsumer.MetricRegistry(),
+		"notifications":    notifications.MetricRegistry(),
+	})
+
+	log.Info("payment consumer started")
+
+	if err = paymentConsumer.Run(ctx); err != nil {
+		panic(fmt.Sprintf("payment consumer error: %v", err))
+	}
+
+	if err = paymentConsumer.Close(); err != nil {
+		panic(fmt.Sprintf("failed to close kafka consumer: %v", err))
+	}
+
+	log.Info("payment consumer stopped")
+
+	if metricsServer != nil {
+		if err = metricsServer.Shutdown(context.Background()); err != nil {
+			log.Error("failed to shutdown metrics server", slog.String("error", err.Error()))
+		}
+	}
+
+	if err = notifications.Close(); err != nil {
+		panic(fmt.Sprintf("failed to close notifications producer: %v", err))
+	}
+
+	log.Info("notifications producer flushed")
+
+	if err = db.Close(); err != nil {
+		panic(fmt.Sprintf("failed to close postgres: %v", err))
+	}
+
+	log.Info("postgres db closed")
+}
+
+// runMetricsServer отдаёт GET /metrics на cfg.MetricsPort. У consumer нет
+// HTTP API, поэтому метрики sarama и уведомлений живут на отдельном порту.
+// Если порт не задан, сервер не запускается и возвращается nil.
+func runMetricsServer(log *slog.Logger, cfg *config.ConsumerConfig, registries map[string]metrics.Registry) *http.Server {
+	if cfg.MetricsPort == 0 {
+		return nil
+	}
+
+	mux := chi.NewRouter()
+	mux.Get("/metrics", metricsHandler.Handler(log, registries))
+
+	server := &http.Server{
+		Handler: mux,
+		Addr:    fmt.Sprintf(":%d", cfg.MetricsPort),
+	}
+
+	go func() {
+		log.Info("metrics server started", slog.String("addr", server.Addr))
+
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Error("failed to run metrics server", slog.String("error", err.Error()))
+		}
+	}()
+
+	return server
+}
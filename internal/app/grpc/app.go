@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	orderServer "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/grpc/order"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/grpc/pb/orderservicev1"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type orderCreation interface {
+	Create(ctx context.Context, order *models.Order) (string, error)
+}
+
+type orderCancellations interface {
+	Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+}
+
+type orderRetrieval interface {
+	OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) ([]models.Order, error)
+	OrderByUUID(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+	StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error)
+	UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error)
+}
+
+type App struct {
+	log        *slog.Logger
+	gRPCServer *grpc.Server
+	health     *health.Server
+	port       int
+}
+
+// NewApp собирает gRPC API: сервис заказов, стандартный health-сервис и
+// reflection для grpcurl и подобных клиентов
+func NewApp(
+	log *slog.Logger,
+	orderCreationSvc orderCreation,
+	orderRetrievalSvc orderRetrieval,
+	orderCancellationsSvc orderCancellations,
+	cfg *config.GRPCConfig,
+) *App {
+	gRPCServer := grpc.NewServer()
+
+	orderservicev1.RegisterOrderServiceServer(
+		gRPCServer,
+		orderServer.NewServer(log, orderCreationSvc, orderRetrievalSvc, orderCancellationsSvc),
+	)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(orderservicev1.OrderService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(gRPCServer, healthServer)
+
+	reflection.Register(gRPCServer)
+
+	return &App{
+		log:        log,
+		gRPCServer: gRPCServer,
+		health:     healthServer,
+		port:       cfg.Port,
+	}
+}
+
+func (a *App) RunWithPanic() {
+	if err := a.run(); err != nil {
+		panic(fmt.Sprintf("failed to run grpc server: %v", err))
+	}
+}
+
+func (a *App) run() error {
+	const op = "app.grpc.run"
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.port))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return a.serve(l)
+}
+
+func (a *App) serve(l net.Listener) error {
+	const op = "app.grpc.serve"
+
+	a.log.With(slog.String("addr", l.Addr().String())).Info("starting grpc server")
+
+	if err := a.gRPCServer.Serve(l); err != nil {
+		a.log.Error("failed to run grpc server", slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Shutdown переводит health в NOT_SERVING и ждёт завершения активных вызовов.
+// Если ctx истекает раньше, оставшиеся вызовы обрываются.
+func (a *App) Shutdown(ctx context.Context) error {
+	log := a.log.With(slog.Int("port", a.port))
+
+	log.Info("shutting down grpc server")
+
+	a.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		a.gRPCServer.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
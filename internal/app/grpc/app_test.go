@@ -0,0 +1,310 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/grpc/pb/orderservicev1"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeOrders - сервисы заказов поверх одного заказа в памяти
+type fakeOrders struct {
+	order models.Order
+
+	created    *models.Order
+	filter     models.OrderFilter
+	cancelErr  error
+	nextCursor *models.OrderCursor
+}
+
+func (f *fakeOrders) Create(_ context.Context, order *models.Order) (string, error) {
+	f.created = order
+	return f.order.OrderUUID.String(), nil
+}
+
+func (f *fakeOrders) Cancel(_ context.Context, orderUUID uuid.UUID, _ models.StatusChange) error {
+	if orderUUID != f.order.OrderUUID {
+		return internalErrors.ErrOrderNotFound
+	}
+
+	return f.cancelErr
+}
+
+func (f *fakeOrders) OrdersByUUIDs(_ context.Context, UUIDs []uuid.UUID) ([]models.Order, error) {
+	result := make([]models.Order, 0, len(UUIDs))
+	for _, id := range UUIDs {
+		if id == f.order.OrderUUID {
+			result = append(result, f.order)
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeOrders) OrderByUUID(_ context.Context, orderUUID uuid.UUID) (*models.Order, error) {
+	if orderUUID != f.order.OrderUUID {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return &f.order, nil
+}
+
+func (f *fakeOrders) StatusHistory(context.Context, uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeOrders) UserOrders(_ context.Context, filter models.OrderFilter) (*models.OrdersPage, error) {
+	f.filter = filter
+
+	return &models.OrdersPage{Orders: []models.Order{f.order}, NextCursor: f.nextCursor}, nil
+}
+
+// newTestApp поднимает сервер поверх bufconn и возвращает подключение к нему
+func newTestApp(t *testing.T, orders *fakeOrders) (*App, *grpc.ClientConn) {
+	t.Helper()
+
+	app := NewApp(slog.New(slog.NewTextHandler(io.Discard, nil)), orders, orders, orders, &config.GRPCConfig{})
+
+	lis := bufconn.Listen(1 << 20)
+	served := make(chan error, 1)
+	go func() {
+		served <- app.serve(lis)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		app.gRPCServer.Stop()
+		require.NoError(t, <-served)
+	})
+
+	return app, conn
+}
+
+func newTestOrder() models.Order {
+	orderUUID := uuid.New()
+
+	return models.Order{
+		OrderUUID: orderUUID,
+		UserUUID:  uuid.New(),
+		Products: []models.Product{
+			{UUID: uuid.New(), OrderUUID: orderUUID, Quantity: 2, UnitPrice: 50, Currency: "RUB"},
+		},
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Card,
+		Currency:    "RUB",
+		TotalAmount: 100,
+		GrandTotal:  100,
+	}
+}
+
+func requireCode(t *testing.T, expected codes.Code, err error) {
+	t.Helper()
+
+	require.Error(t, err)
+	require.Equal(t, expected, status.Code(err), err.Error())
+}
+
+func TestCreateOrder(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	_, conn := newTestApp(t, orders)
+	client := orderservicev1.NewOrderServiceClient(conn)
+	ctx := context.Background()
+
+	req := &orderservicev1.CreateOrderRequest{
+		UserUuid: uuid.NewString(),
+		Products: []*orderservicev1.ProductLine{
+			{ProductUuid: uuid.NewString(), Quantity: 3, UnitPrice: 100, Currency: "RUB"},
+		},
+		PaymentType: orderv1.PaymentType_PAYMENT_TYPE_POINTS,
+		WithPoints:  200,
+	}
+
+	resp, err := client.CreateOrder(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, orders.order.OrderUUID.String(), resp.GetOrderUuid())
+	require.Equal(t, models.Points, orders.created.PaymentType)
+	require.Equal(t, 200, orders.created.WithPoints)
+
+	req.WithPoints = 500
+	_, err = client.CreateOrder(ctx, req)
+	requireCode(t, codes.InvalidArgument, err)
+
+	req.WithPoints = 0
+	req.Products = append(req.Products, &orderservicev1.ProductLine{
+		ProductUuid: uuid.NewString(), Quantity: 1, UnitPrice: 100, Currency: "USD",
+	})
+	_, err = client.CreateOrder(ctx, req)
+	requireCode(t, codes.InvalidArgument, err)
+
+	_, err = client.CreateOrder(ctx, &orderservicev1.CreateOrderRequest{UserUuid: uuid.NewString()})
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestGetOrders(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	_, conn := newTestApp(t, orders)
+	client := orderservicev1.NewOrderServiceClient(conn)
+	ctx := context.Background()
+
+	orderUUID := orders.order.OrderUUID.String()
+
+	got, err := client.GetOrder(ctx, &orderservicev1.GetOrderRequest{OrderUuid: orderUUID})
+	require.NoError(t, err)
+	require.Equal(t, orderUUID, got.GetOrder().GetOrderUuid())
+	require.Equal(t, orderv1.OrderStatus_ORDER_STATUS_CREATED, got.GetOrder().GetStatus())
+	require.Len(t, got.GetOrder().GetProducts(), 1)
+
+	_, err = client.GetOrder(ctx, &orderservicev1.GetOrderRequest{OrderUuid: uuid.NewString()})
+	requireCode(t, codes.NotFound, err)
+
+	_, err = client.GetOrder(ctx, &orderservicev1.GetOrderRequest{OrderUuid: "bad"})
+	requireCode(t, codes.InvalidArgument, err)
+
+	batch, err := client.BatchGetOrders(ctx, &orderservicev1.BatchGetOrdersRequest{
+		OrderUuids: []string{orderUUID, uuid.NewString()},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch.GetOrders(), 1)
+
+	_, err = client.BatchGetOrders(ctx, &orderservicev1.BatchGetOrdersRequest{})
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestCancelOrderErrors(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	_, conn := newTestApp(t, orders)
+	client := orderservicev1.NewOrderServiceClient(conn)
+	ctx := context.Background()
+
+	req := &orderservicev1.CancelOrderRequest{OrderUuid: orders.order.OrderUUID.String()}
+
+	_, err := client.CancelOrder(ctx, req)
+	require.NoError(t, err)
+
+	orders.cancelErr = &models.StatusTransitionError{From: models.OrderStatusCanceled, To: models.OrderStatusCanceled}
+	_, err = client.CancelOrder(ctx, req)
+	requireCode(t, codes.FailedPrecondition, err)
+	require.Equal(t, internalErrors.ErrOrderAlreadyCanceled.Error(), status.Convert(err).Message())
+
+	// внутренняя причина не уходит клиенту
+	orders.cancelErr = errors.New("repository.order.Cancel: pq: connection refused")
+	_, err = client.CancelOrder(ctx, req)
+	requireCode(t, codes.Internal, err)
+	require.NotContains(t, status.Convert(err).Message(), "pq:")
+
+	_, err = client.CancelOrder(ctx, &orderservicev1.CancelOrderRequest{OrderUuid: uuid.NewString()})
+	requireCode(t, codes.NotFound, err)
+
+	// инициатора payment выставляет только consumer оплат
+	_, err = client.CancelOrder(ctx, &orderservicev1.CancelOrderRequest{
+		OrderUuid: orders.order.OrderUUID.String(),
+		Actor:     models.ActorPayment,
+	})
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestListOrders(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	orders.nextCursor = &models.OrderCursor{CreatedAt: time.Now().UTC(), OrderUUID: orders.order.OrderUUID}
+
+	_, conn := newTestApp(t, orders)
+	client := orderservicev1.NewOrderServiceClient(conn)
+	ctx := context.Background()
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	req := &orderservicev1.ListOrdersRequest{
+		UserUuid:    orders.order.UserUUID.String(),
+		Statuses:    []orderv1.OrderStatus{orderv1.OrderStatus_ORDER_STATUS_PAID},
+		PaymentType: orderv1.PaymentType_PAYMENT_TYPE_CARD,
+		CreatedFrom: timestamppb.New(from),
+		Limit:       1,
+	}
+
+	resp, err := client.ListOrders(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrders(), 1)
+	require.NotEmpty(t, resp.GetNextCursor())
+	require.Equal(t, models.OrderFilter{
+		UserUUID:    orders.order.UserUUID,
+		Statuses:    []models.OrderStatus{models.OrderStatusPaid},
+		PaymentType: models.Card,
+		CreatedFrom: from,
+		Limit:       1,
+	}, orders.filter)
+
+	req.Cursor = resp.GetNextCursor()
+	_, err = client.ListOrders(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, orders.nextCursor.OrderUUID, orders.filter.After.OrderUUID)
+	require.True(t, orders.nextCursor.CreatedAt.Equal(orders.filter.After.CreatedAt))
+
+	req.Limit = 1000
+	_, err = client.ListOrders(ctx, req)
+	requireCode(t, codes.InvalidArgument, err)
+}
+
+func TestHealthAndShutdown(t *testing.T) {
+	app, conn := newTestApp(t, &fakeOrders{order: newTestOrder()})
+	ctx := context.Background()
+
+	healthClient := healthpb.NewHealthClient(conn)
+	resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{
+		Service: orderservicev1.OrderService_ServiceDesc.ServiceName,
+	})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	require.NoError(t, app.Shutdown(shutdownCtx))
+
+	_, err = healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.Error(t, err)
+}
+
+func TestReflection(t *testing.T) {
+	_, conn := newTestApp(t, &fakeOrders{order: newTestOrder()})
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	services := make([]string, 0)
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	require.Contains(t, services, orderservicev1.OrderService_ServiceDesc.ServiceName)
+	require.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+}
@@ -2,17 +2,25 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/rcrowley/go-metrics"
+	"github.com/tumbleweedd/two_services_system/order_service/api/openapi"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	metricsHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/metrics"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/middleware"
 	cancelHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/order/cancel"
 	createHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/order/create"
 	getHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/order/get"
+	pointsHandler "github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/points"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
@@ -21,47 +29,118 @@ type orderCreation interface {
 }
 
 type orderCancellations interface {
-	Cancel(ctx context.Context, orderUUID uuid.UUID) error
+	Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+	CancelLines(
+		ctx context.Context,
+		orderUUID uuid.UUID,
+		productUUIDs []uuid.UUID,
+		change models.StatusChange,
+	) (*models.LinesCancellation, error)
 }
 
 type orderRetrieval interface {
 	OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) ([]models.Order, error)
 	OrderByUUID(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+	StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error)
+	UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error)
+}
+
+type pointsBalance interface {
+	Balance(ctx context.Context, userUUID uuid.UUID) (*models.PointsBalance, error)
+}
+
+type idempotencyStore interface {
+	Reserve(
+		ctx context.Context,
+		key string,
+		owner uuid.UUID,
+		requestHash string,
+		ttl time.Duration,
+		lockTimeout time.Duration,
+	) (*models.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, owner uuid.UUID, response models.IdempotentResponse) error
+	Release(ctx context.Context, key string, owner uuid.UUID) error
+	PurgeExpired(ctx context.Context, batchSize int) (int64, error)
 }
 
 type App struct {
-	log        *slog.Logger
-	httpServer *http.Server
+	log         *slog.Logger
+	httpServer  *http.Server
+	idempotency *middleware.Idempotency
 }
 
+// NewApp собирает HTTP API. Запросы проверяются по OpenAPI-описанию из
+// api/openapi, opts настраивают эту проверку. metricRegistries отдаются на
+// GET /metrics.
 func NewApp(
 	log *slog.Logger,
 	orderCreationSvc orderCreation,
 	orderRetrievalSvc orderRetrieval,
 	orderCancellationsSvc orderCancellations,
+	pointsSvc pointsBalance,
+	idempotencyStore idempotencyStore,
+	metricRegistries map[string]metrics.Registry,
 	cfg *config.HTTPConfig,
-) *App {
+	opts ...middleware.OpenAPIOption,
+) (*App, error) {
+	const op = "app.http.NewApp"
+
+	doc, err := openapi.Load()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshal spec: %w", op, err)
+	}
+
+	validator, err := middleware.NewOpenAPI(log, doc, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
 	mux := chi.NewRouter()
+	mux.NotFound(problem.NotFound)
+	mux.MethodNotAllowed(problem.MethodNotAllowed)
+	mux.Use(validator.Handler)
 
 	cancelH := cancelHandler.NewHandler(log, orderCancellationsSvc)
 	createH := createHandler.NewHandler(log, orderCreationSvc)
 	getH := getHandler.NewHandler(log, orderRetrievalSvc)
+	pointsH := pointsHandler.NewHandler(log, pointsSvc)
+
+	idempotency := middleware.NewIdempotency(log, idempotencyStore, cfg.Idempotency)
+
+	mux.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(spec)
+	})
+
+	mux.Get("/metrics", metricsHandler.Handler(log, metricRegistries))
 
 	mux.Route("/order", func(r chi.Router) {
 		r.Post("/cancel", cancelH.Cancel)
-		r.Post("/", createH.Create)
+		r.With(idempotency.Handler).Post("/", createH.Create)
 		r.Get("/", getH.OrdersByUUIDs)
+		r.Get("/{uuid}", getH.OrderByUUID)
+		r.Get("/{uuid}/history", getH.StatusHistory)
+		r.Post("/{uuid}/lines/cancel", cancelH.CancelLines)
 	})
 
+	mux.Get("/users/{user_uuid}/orders", getH.UserOrders)
+	mux.Get("/users/{user_uuid}/points", pointsH.Balance)
+
 	httpServer := &http.Server{
 		Handler: mux,
 		Addr:    fmt.Sprintf(":%d", cfg.Port),
 	}
 
 	return &App{
-		log:        log,
-		httpServer: httpServer,
-	}
+		log:         log,
+		httpServer:  httpServer,
+		idempotency: idempotency,
+	}, nil
 }
 
 func (a *App) RunWithPanic() {
@@ -81,6 +160,12 @@ func (a *App) run() error {
 	return nil
 }
 
+// RunIdempotencyPurge удаляет истёкшие ключи идемпотентности, пока не будет
+// отменён ctx
+func (a *App) RunIdempotencyPurge(ctx context.Context) {
+	a.idempotency.RunPurge(ctx)
+}
+
 func (a *App) Shutdown(ctx context.Context) error {
 	log := a.log.With(slog.String("port", a.httpServer.Addr))
 
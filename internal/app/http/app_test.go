@@ -0,0 +1,262 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/api/openapi"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/middleware"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// fakeOrders - сервисы заказов поверх одного заказа в памяти
+type fakeOrders struct {
+	order   models.Order
+	created int
+}
+
+func (f *fakeOrders) Create(context.Context, *models.Order) (string, error) {
+	f.created++
+	return f.order.OrderUUID.String(), nil
+}
+
+func (f *fakeOrders) Cancel(_ context.Context, orderUUID uuid.UUID, _ models.StatusChange) error {
+	if orderUUID != f.order.OrderUUID {
+		return internalErrors.ErrOrderNotFound
+	}
+
+	return fmt.Errorf("service.order.Cancel: %w",
+		&models.StatusTransitionError{From: models.OrderStatusCanceled, To: models.OrderStatusCanceled})
+}
+
+func (f *fakeOrders) CancelLines(
+	_ context.Context,
+	orderUUID uuid.UUID,
+	productUUIDs []uuid.UUID,
+	_ models.StatusChange,
+) (*models.LinesCancellation, error) {
+	if orderUUID != f.order.OrderUUID {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	order := f.order
+	cancellation, err := order.CancelLines(productUUIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cancellation, nil
+}
+
+func (f *fakeOrders) OrdersByUUIDs(_ context.Context, UUIDs []uuid.UUID) ([]models.Order, error) {
+	result := make([]models.Order, 0, len(UUIDs))
+	for _, id := range UUIDs {
+		if id == f.order.OrderUUID {
+			result = append(result, f.order)
+		}
+	}
+
+	return result, nil
+}
+
+func (f *fakeOrders) OrderByUUID(_ context.Context, orderUUID uuid.UUID) (*models.Order, error) {
+	if orderUUID != f.order.OrderUUID {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return &f.order, nil
+}
+
+func (f *fakeOrders) StatusHistory(_ context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	if orderUUID != f.order.OrderUUID {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return []models.StatusHistoryEntry{{
+		OrderUUID: orderUUID,
+		ToStatus:  models.OrderStatusCreated,
+		Actor:     models.ActorCustomer,
+		Reason:    "order created",
+		ChangedAt: time.Now(),
+	}}, nil
+}
+
+func (f *fakeOrders) Balance(_ context.Context, userUUID uuid.UUID) (*models.PointsBalance, error) {
+	return &models.PointsBalance{UserUUID: userUUID, Balance: 500, Held: 200, Available: 300}, nil
+}
+
+func (f *fakeOrders) UserOrders(context.Context, models.OrderFilter) (*models.OrdersPage, error) {
+	return &models.OrdersPage{
+		Orders:     []models.Order{f.order},
+		NextCursor: &models.OrderCursor{CreatedAt: time.Now(), OrderUUID: f.order.OrderUUID},
+	}, nil
+}
+
+// responseErrors собирает ответы, не совпавшие с OpenAPI-описанием
+type responseErrors struct {
+	mu   sync.Mutex
+	errs []string
+}
+
+func (e *responseErrors) report(r *http.Request, status int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.errs = append(e.errs, fmt.Sprintf("%s %s -> %d: %v", r.Method, r.URL, status, err))
+}
+
+func newTestApp(t *testing.T, orders *fakeOrders, opts ...middleware.OpenAPIOption) http.Handler {
+	t.Helper()
+
+	app, err := NewApp(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		orders,
+		orders,
+		orders,
+		orders,
+		nil,
+		map[string]metrics.Registry{"notifications": metrics.NewRegistry()},
+		&config.HTTPConfig{},
+		opts...,
+	)
+	require.NoError(t, err)
+
+	return app.httpServer.Handler
+}
+
+func newTestOrder() models.Order {
+	orderUUID := uuid.New()
+
+	return models.Order{
+		OrderUUID: orderUUID,
+		UserUUID:  uuid.New(),
+		Products: []models.Product{
+			{UUID: uuid.New(), OrderUUID: orderUUID, Quantity: 2, UnitPrice: 50, Currency: "RUB"},
+		},
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Card,
+		Currency:    "RUB",
+		TotalAmount: 100,
+		GrandTotal:  100,
+	}
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	routes, ok := newTestApp(t, &fakeOrders{}).(chi.Routes)
+	require.True(t, ok)
+
+	walk := func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+
+		path := doc.Paths.Value(route)
+		if path == nil || path.GetOperation(method) == nil {
+			t.Errorf("route %s %s is not described in api/openapi/openapi.yaml", method, route)
+		}
+
+		return nil
+	}
+	require.NoError(t, chi.Walk(routes, walk))
+}
+
+func TestResponsesMatchSpec(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	errs := &responseErrors{}
+	server := newTestApp(t, orders, middleware.WithResponseValidation(errs.report))
+
+	orderUUID := orders.order.OrderUUID.String()
+	createBody := fmt.Sprintf(`{"user_uuid": %q, "products": [{"uuid": %q, "quantity": 2, "unit_price": 50, "currency": "RUB"}], "payment_type": "card"}`,
+		uuid.NewString(), uuid.NewString())
+	mixedCurrencyBody := fmt.Sprintf(`{"user_uuid": %q, "products": [{"uuid": %q, "quantity": 1, "unit_price": 50, "currency": "RUB"}, {"uuid": %q, "quantity": 1, "unit_price": 50, "currency": "USD"}], "payment_type": "card"}`,
+		uuid.NewString(), uuid.NewString(), uuid.NewString())
+	linesBody := fmt.Sprintf(`{"product_uuids": [%q], "reason": "out of stock"}`, orders.order.Products[0].UUID)
+
+	tCases := []struct {
+		method string
+		target string
+		body   string
+		status int
+		code   string
+	}{
+		{http.MethodGet, "/openapi.json", "", http.StatusOK, ""},
+		{http.MethodGet, "/metrics", "", http.StatusOK, ""},
+		{http.MethodPost, "/order/", createBody, http.StatusOK, ""},
+		{http.MethodPost, "/order/", `{"user_uuid": "bad"}`, http.StatusBadRequest, problem.CodeInvalidRequest},
+		{http.MethodPost, "/order/", mixedCurrencyBody, http.StatusUnprocessableEntity, problem.CodeMixedCurrency},
+		{http.MethodGet, "/order?uuid=" + orderUUID, "", http.StatusOK, ""},
+		{http.MethodGet, "/order/", `{"uuids": [` + fmt.Sprintf("%q", orderUUID) + `]}`, http.StatusOK, ""},
+		{http.MethodGet, "/order/" + orderUUID, "", http.StatusOK, ""},
+		{http.MethodGet, "/order/" + uuid.NewString(), "", http.StatusNotFound, ""},
+		{http.MethodGet, "/order/" + orderUUID + "/history", "", http.StatusOK, ""},
+		{http.MethodPost, "/order/cancel", fmt.Sprintf(`{"order_uuid": %q}`, orderUUID), http.StatusConflict, ""},
+		{http.MethodPost, "/order/cancel", fmt.Sprintf(`{"order_uuid": %q}`, uuid.NewString()), http.StatusNotFound, ""},
+		{http.MethodPost, "/order/cancel", fmt.Sprintf(`{"order_uuid": %q, "actor": "payment"}`, orderUUID), http.StatusBadRequest, ""},
+		{http.MethodPost, "/order/" + orderUUID + "/lines/cancel", linesBody, http.StatusOK, ""},
+		{http.MethodPost, "/order/" + orderUUID + "/lines/cancel", `{"product_uuids": [` + fmt.Sprintf("%q", uuid.NewString()) + `]}`, http.StatusUnprocessableEntity, ""},
+		{http.MethodPost, "/order/" + orderUUID + "/lines/cancel", `{"product_uuids": []}`, http.StatusBadRequest, ""},
+		{http.MethodGet, "/users/" + uuid.NewString() + "/orders?status=created&limit=1", "", http.StatusOK, ""},
+		{http.MethodGet, "/users/" + uuid.NewString() + "/orders?limit=1000", "", http.StatusBadRequest, ""},
+		{http.MethodGet, "/users/" + uuid.NewString() + "/points", "", http.StatusOK, ""},
+		{http.MethodGet, "/users/bad/points", "", http.StatusBadRequest, ""},
+	}
+
+	for _, tCase := range tCases {
+		r := httptest.NewRequest(tCase.method, tCase.target, strings.NewReader(tCase.body))
+		if tCase.body != "" {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		require.Equal(t, tCase.status, w.Code, "%s %s: %s", tCase.method, tCase.target, w.Body.String())
+
+		if tCase.code != "" {
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			require.Equal(t, tCase.code, p.Code, "%s %s", tCase.method, tCase.target)
+		}
+	}
+
+	require.Empty(t, errs.errs)
+}
+
+func TestRequestRejectedBySpec(t *testing.T) {
+	orders := &fakeOrders{order: newTestOrder()}
+	server := newTestApp(t, orders)
+
+	body := fmt.Sprintf(`{"user_uuid": %q, "products": [], "payment_type": "cash"}`, uuid.NewString())
+	r := httptest.NewRequest(http.MethodPost, "/order/", strings.NewReader(body))
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, problem.CodeInvalidRequest, p.Code)
+	require.NotContains(t, p.Detail, "Schema:")
+
+	require.Zero(t, orders.created)
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/cache_impl"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/repository"
+	orderCancellationsService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/cancel"
+	orderCreationService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/create"
+	orderRetrievalService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/get"
+	pointsService "github.com/tumbleweedd/two_services_system/order_service/internal/services/points"
+	"github.com/tumbleweedd/two_services_system/order_service/pkg/brokers/kafka/producer"
+)
+
+// pointsClient ходит в HTTP API, собранное поверх настоящей базы
+type pointsClient struct {
+	t      *testing.T
+	server http.Handler
+}
+
+func (c *pointsClient) do(method string, target string, body string, status int) []byte {
+	c.t.Helper()
+
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	w := httptest.NewRecorder()
+	c.server.ServeHTTP(w, r)
+	require.Equal(c.t, status, w.Code, "%s %s: %s", method, target, w.Body.String())
+
+	return w.Body.Bytes()
+}
+
+func (c *pointsClient) createOrder(userUUID uuid.UUID, withPoints int, status int) uuid.UUID {
+	c.t.Helper()
+
+	body := fmt.Sprintf(`{"user_uuid": %q, "products": [{"uuid": %q, "quantity": 1, "unit_price": 1000, "currency": "RUB"}], "payment_type": "points", "with_points": %d}`,
+		userUUID, uuid.NewString(), withPoints)
+
+	response := c.do(http.MethodPost, "/order/", body, status)
+	if status != http.StatusOK {
+		return uuid.Nil
+	}
+
+	var created struct {
+		OrderUUID uuid.UUID `json:"order_uuid"`
+	}
+	require.NoError(c.t, json.Unmarshal(response, &created))
+
+	return created.OrderUUID
+}
+
+func (c *pointsClient) cancelOrder(orderUUID uuid.UUID) {
+	c.t.Helper()
+
+	c.do(http.MethodPost, "/order/cancel", fmt.Sprintf(`{"order_uuid": %q}`, orderUUID), http.StatusOK)
+}
+
+func (c *pointsClient) requireBalance(userUUID uuid.UUID, balance, held uint64) {
+	c.t.Helper()
+
+	var got models.PointsBalance
+	require.NoError(c.t, json.Unmarshal(c.do(http.MethodGet, "/users/"+userUUID.String()+"/points", "", http.StatusOK), &got))
+	require.Equal(c.t, balance, got.Balance)
+	require.Equal(c.t, held, got.Held)
+	require.Equal(c.t, balance-held, got.Available)
+}
+
+// TestPointsEndToEnd проводит баллы через HTTP API: удержание при создании
+// заказа, списание при оплате, снятие удержания и возврат при отмене.
+// Начисление и оплата не входят в публичное API, поэтому они проводятся через
+// репозиторий, как это делают внутренние вызывающие и consumer.
+func TestPointsEndToEnd(t *testing.T) {
+	db := testdb.New(t)
+	testdb.Truncate(t, db,
+		"order", "order_products", "order_status_history", "outbox",
+		"points_account", "points_hold", "points_ledger", "idempotency_key",
+	)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := repository.NewRepository(log, db)
+	cache := cache_impl.NewCache(expirable.NewLRU[uuid.UUID, *models.Order](5, nil, time.Minute), log)
+	notifications := producer.NopProducer{}
+
+	app, err := NewApp(
+		log,
+		orderCreationService.New(log, cache, repo, repo, notifications),
+		orderRetrievalService.New(log, cache, repo),
+		orderCancellationsService.New(log, repo, notifications),
+		pointsService.New(log, repo),
+		repo,
+		nil,
+		&config.HTTPConfig{},
+	)
+	require.NoError(t, err)
+
+	client := &pointsClient{t: t, server: app.httpServer.Handler}
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	client.requireBalance(userUUID, 0, 0)
+	require.NoError(t, repo.Credit(ctx, userUUID, 500, "welcome bonus"))
+	client.requireBalance(userUUID, 500, 0)
+
+	paid := client.createOrder(userUUID, 300, http.StatusOK)
+	client.requireBalance(userUUID, 500, 300)
+
+	client.createOrder(userUUID, 300, http.StatusUnprocessableEntity)
+	client.requireBalance(userUUID, 500, 300)
+
+	released := client.createOrder(userUUID, 200, http.StatusOK)
+	client.requireBalance(userUUID, 500, 500)
+
+	require.NoError(t, repo.MarkPaid(ctx, paid, models.StatusChange{Actor: models.ActorPayment}))
+	client.requireBalance(userUUID, 200, 200)
+
+	client.cancelOrder(released)
+	client.requireBalance(userUUID, 200, 0)
+
+	// отмена оплаченного заказа возвращает списанные баллы
+	client.cancelOrder(paid)
+	client.requireBalance(userUUID, 500, 0)
+
+	var entries []string
+	require.NoError(t, db.Select(&entries,
+		`SELECT entry_type FROM "points_ledger" WHERE user_uuid = $1 ORDER BY id`, userUUID))
+	require.Equal(t, []string{"credit", "hold", "hold", "capture", "release", "refund"}, entries)
+}
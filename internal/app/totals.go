@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/repository"
+	orderTotalsService "github.com/tumbleweedd/two_services_system/order_service/internal/services/order/totals"
+	"github.com/tumbleweedd/two_services_system/order_service/pkg/logger"
+)
+
+// RunTotalsCheck печатает заказы, хранимые суммы которых не сходятся со
+// строками. Возвращает код выхода: 0 - расхождений нет, 1 - найдены
+// расхождения, 2 - проверка не завершилась.
+func RunTotalsCheck() int {
+	// флаги разбирает config.InitConfig, поэтому свои объявляются до него
+	batchSize := flag.Int("batch-size", 500, "orders per query")
+
+	cfg := config.InitConfig()
+
+	log := logger.SetupLogger(cfg.Env)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	db := setupDatabase(ctx, log, &cfg)
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Error("failed to close postgres", slog.String("error", err.Error()))
+		}
+	}()
+
+	checker := orderTotalsService.New(log, repository.NewOrderRepository(log, db.GetDB()), *batchSize)
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	writeMismatchHeader(out)
+
+	found, err := checker.Check(ctx, func(m models.TotalsMismatch) {
+		writeMismatch(out, m)
+	})
+	if flushErr := out.Flush(); flushErr != nil {
+		log.Error("failed to write report", slog.String("error", flushErr.Error()))
+	}
+	if err != nil {
+		log.Error("totals check failed", slog.String("error", err.Error()))
+		return 2
+	}
+
+	if found > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func writeMismatchHeader(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "order_uuid\tcurrency\ttotal_amount\tpromo_discount\tpromo_discount_line\tpoints_discount\tgrand_total\tlines_total\tlines_currency")
+}
+
+func writeMismatch(w io.Writer, m models.TotalsMismatch) {
+	_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n",
+		m.OrderUUID, m.Currency, m.TotalAmount, m.PromoDiscount, m.PromoDiscountLine, m.PointsDiscount, m.GrandTotal,
+		m.LinesTotal, m.LinesCurrency)
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -10,12 +13,36 @@ import (
 type Config struct {
 	Env      string         `yaml:"env" env-default:"local"`
 	HTTP     HTTPConfig     `yaml:"http"`
+	GRPC     GRPCConfig     `yaml:"grpc"`
 	Postgres PostgresConfig `yaml:"postgres"`
 	Kafka    KafkaConfig    `yaml:"kafka"`
+	Outbox   OutboxConfig   `yaml:"outbox"`
+	Consumer ConsumerConfig `yaml:"consumer"`
 }
 
 type HTTPConfig struct {
 	Port int `yaml:"port"`
+
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+}
+
+type GRPCConfig struct {
+	Port int `yaml:"port" env-default:"44044"`
+	// ShutdownTimeout - сколько GracefulStop ждёт завершения активных вызовов
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+}
+
+type IdempotencyConfig struct {
+	// TTL - сколько хранится ответ на запрос с ключом идемпотентности
+	TTL time.Duration `yaml:"ttl" env-default:"24h"`
+	// WaitTimeout - сколько конкурентный запрос с тем же ключом ждёт завершения первого
+	WaitTimeout time.Duration `yaml:"wait_timeout" env-default:"5s"`
+	// LockTimeout - через сколько незавершённый запрос считается брошенным и ключ можно занять снова
+	LockTimeout time.Duration `yaml:"lock_timeout" env-default:"1m"`
+	// PurgeInterval - как часто удаляются истёкшие ключи, 0 отключает очистку
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	// PurgeBatchSize - сколько ключей удаляется одним запросом
+	PurgeBatchSize int `yaml:"purge_batch_size" env-default:"1000"`
 }
 
 type PostgresConfig struct {
@@ -28,10 +55,55 @@ type PostgresConfig struct {
 }
 
 type KafkaConfig struct {
-	BrokerList       []string `yaml:"broker_list"`
-	OrderEventTopic  string   `yaml:"order_event_topic"`
-	StatusEventTopic string   `yaml:"status_event_topic"`
-	Port             string   `yaml:"port"`
+	BrokerList        []string `yaml:"broker_list"`
+	OrderEventTopic   string   `yaml:"order_event_topic"`
+	StatusEventTopic  string   `yaml:"status_event_topic"`
+	PaymentEventTopic string   `yaml:"payment_event_topic" env-default:"payment_topic"`
+	ConsumerGroup     string   `yaml:"consumer_group" env-default:"order_service"`
+	// Notifications включает best-effort уведомления в OrderEventTopic и StatusEventTopic
+	// сразу после изменения заказа, не дожидаясь релея outbox
+	Notifications bool `yaml:"notifications" env-default:"false"`
+	// Encoding - формат сообщений: json, protobuf или avro
+	Encoding string `yaml:"encoding" env-default:"json"`
+	// SchemaRegistryDir - каталог файлового реестра Avro-схем
+	SchemaRegistryDir string `yaml:"schema_registry_dir" env-default:"schemas"`
+	// CloudEventsMode - binding конверта CloudEvents: binary (атрибуты в заголовках) или structured
+	CloudEventsMode string `yaml:"cloudevents_mode" env-default:"binary"`
+	// CloudEventsSource - атрибут source событий сервиса
+	CloudEventsSource string `yaml:"cloudevents_source" env-default:"/order_service"`
+	Port              string `yaml:"port"`
+}
+
+type ConsumerConfig struct {
+	// MetricsPort - порт HTTP-сервера с GET /metrics у consumer, 0 отключает сервер
+	MetricsPort int `yaml:"metrics_port" env-default:"0"`
+}
+
+type OutboxConfig struct {
+	// PollInterval - пауза между опросами таблицы outbox, пока в ней есть события
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+	// BatchSize - сколько событий публикуется за одну транзакцию
+	BatchSize int `yaml:"batch_size" env-default:"100"`
+	// MaxIdleBackoff - верхняя граница паузы, до которой растёт интервал опроса пустой таблицы
+	MaxIdleBackoff time.Duration `yaml:"max_idle_backoff" env-default:"30s"`
+	// MaxAttempts - после стольких неудачных попыток событие переносится в outbox_dead_letter
+	MaxAttempts int `yaml:"max_attempts" env-default:"10"`
+	// RetryBaseDelay и RetryMaxDelay задают экспоненциальную паузу перед повторной отправкой события
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" env-default:"1s"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay" env-default:"10m"`
+
+	Retention OutboxRetentionConfig `yaml:"retention"`
+}
+
+type OutboxRetentionConfig struct {
+	// Age - отправленные события старше этого возраста удаляются, 0 отключает очистку
+	Age time.Duration `yaml:"age" env-default:"168h"`
+	// Interval - как часто запускается очистка
+	Interval time.Duration `yaml:"interval" env-default:"1h"`
+	// BatchSize - сколько строк удаляется одним запросом, чтобы не держать долгие блокировки
+	BatchSize int `yaml:"batch_size" env-default:"1000"`
+	// Archive - переносить строки в outbox_archive вместо удаления
+	Archive bool `yaml:"archive" env-default:"false"`
 }
 
 func InitConfig() Config {
@@ -45,14 +117,107 @@ func InitConfig() Config {
 		panic("config file does not exist: " + configPath)
 	}
 
-	var cfg Config
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		panic("empty config path: " + err.Error())
+	cfg, err := Load(configPath)
+	if err != nil {
+		panic("failed to load config: " + err.Error())
 	}
 
 	return cfg
 }
 
+// Load читает конфиг из файла path и проверяет значения, с которыми сервис
+// не сможет работать
+func Load(path string) (Config, error) {
+	const op = "config.Load"
+
+	var cfg Config
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	if err := c.HTTP.Idempotency.validate(); err != nil {
+		return err
+	}
+	if err := c.Outbox.validate(); err != nil {
+		return err
+	}
+
+	return c.Outbox.Retention.validate()
+}
+
+// validate проверяет настройки очистки ключей, только если она включена:
+// нулевой PurgeInterval отключает очистку, а с нулевым PurgeBatchSize
+// PurgeExpired не выходит из цикла
+func (c *IdempotencyConfig) validate() error {
+	if c.PurgeInterval < 0 {
+		return errors.New("http.idempotency.purge_interval should not be negative")
+	}
+	if c.PurgeInterval == 0 {
+		return nil
+	}
+
+	if c.PurgeBatchSize <= 0 {
+		return fmt.Errorf("http.idempotency.purge_batch_size should be positive, got %d", c.PurgeBatchSize)
+	}
+
+	return nil
+}
+
+// validate проверяет настройки релея: с нулевым BatchSize любая пачка
+// считается полной, а с нулевым PollInterval пауза между опросами не растёт,
+// и в обоих случаях Run опрашивает таблицу без остановки. С нулевым
+// MaxAttempts первая же ошибка отправки уводит событие в dead letter, а с
+// нулевым RetryBaseDelay повторы идут без паузы.
+func (c *OutboxConfig) validate() error {
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("outbox.batch_size should be positive, got %d", c.BatchSize)
+	}
+	if c.PollInterval <= 0 {
+		return fmt.Errorf("outbox.poll_interval should be positive, got %s", c.PollInterval)
+	}
+	if c.MaxIdleBackoff < c.PollInterval {
+		return fmt.Errorf("outbox.max_idle_backoff %s should not be less than poll_interval %s",
+			c.MaxIdleBackoff, c.PollInterval)
+	}
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("outbox.max_attempts should be positive, got %d", c.MaxAttempts)
+	}
+	if c.RetryBaseDelay <= 0 {
+		return fmt.Errorf("outbox.retry_base_delay should be positive, got %s", c.RetryBaseDelay)
+	}
+
+	return nil
+}
+
+// validate проверяет настройки очистки, только если она включена: нулевой
+// Interval роняет time.NewTicker, а с нулевым BatchSize Cleanup не выходит
+// из цикла
+func (c *OutboxRetentionConfig) validate() error {
+	if c.Age < 0 {
+		return errors.New("outbox.retention.age should not be negative")
+	}
+	if c.Age == 0 {
+		return nil
+	}
+
+	if c.Interval <= 0 {
+		return fmt.Errorf("outbox.retention.interval should be positive, got %s", c.Interval)
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("outbox.retention.batch_size should be positive, got %d", c.BatchSize)
+	}
+
+	return nil
+}
+
 func getConfigPath() string {
 	var path string
 
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "env: local\n"))
+	require.NoError(t, err)
+	require.Equal(t, 1000, cfg.Outbox.Retention.BatchSize)
+	require.Equal(t, time.Hour, cfg.Outbox.Retention.Interval)
+	require.Equal(t, time.Hour, cfg.HTTP.Idempotency.PurgeInterval)
+	require.Equal(t, 1000, cfg.HTTP.Idempotency.PurgeBatchSize)
+}
+
+func TestLoadRetentionError(t *testing.T) {
+	_, err := Load(writeConfig(t, "outbox:\n  retention:\n    batch_size: -1\n"))
+	require.Error(t, err)
+
+	_, err = Load(writeConfig(t, "outbox:\n  retention:\n    interval: -1m\n"))
+	require.Error(t, err)
+}
+
+func TestRetentionValidate(t *testing.T) {
+	valid := OutboxRetentionConfig{Age: time.Hour, Interval: time.Minute, BatchSize: 100}
+	require.NoError(t, valid.validate())
+
+	// выключенная очистка не проверяет остальные настройки
+	require.NoError(t, (&OutboxRetentionConfig{}).validate())
+
+	tCases := []struct {
+		name   string
+		modify func(cfg *OutboxRetentionConfig)
+	}{
+		{name: "zero_batch_size", modify: func(cfg *OutboxRetentionConfig) { cfg.BatchSize = 0 }},
+		{name: "negative_batch_size", modify: func(cfg *OutboxRetentionConfig) { cfg.BatchSize = -1 }},
+		{name: "zero_interval", modify: func(cfg *OutboxRetentionConfig) { cfg.Interval = 0 }},
+		{name: "negative_interval", modify: func(cfg *OutboxRetentionConfig) { cfg.Interval = -time.Minute }},
+		{name: "negative_age", modify: func(cfg *OutboxRetentionConfig) { cfg.Age = -time.Hour }},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			cfg := valid
+			tCase.modify(&cfg)
+			require.Error(t, cfg.validate())
+		})
+	}
+}
+
+func TestIdempotencyValidate(t *testing.T) {
+	valid := IdempotencyConfig{PurgeInterval: time.Hour, PurgeBatchSize: 100}
+	require.NoError(t, valid.validate())
+
+	// выключенная очистка не проверяет размер пачки
+	require.NoError(t, (&IdempotencyConfig{}).validate())
+
+	tCases := []struct {
+		name   string
+		modify func(cfg *IdempotencyConfig)
+	}{
+		{name: "zero_batch_size", modify: func(cfg *IdempotencyConfig) { cfg.PurgeBatchSize = 0 }},
+		{name: "negative_interval", modify: func(cfg *IdempotencyConfig) { cfg.PurgeInterval = -time.Minute }},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			cfg := valid
+			tCase.modify(&cfg)
+			require.Error(t, cfg.validate())
+		})
+	}
+
+	_, err := Load(writeConfig(t, "http:\n  idempotency:\n    purge_batch_size: -1\n"))
+	require.Error(t, err)
+}
+
+func TestOutboxValidate(t *testing.T) {
+	valid := OutboxConfig{
+		PollInterval: time.Second, BatchSize: 100, MaxIdleBackoff: time.Minute,
+		MaxAttempts: 10, RetryBaseDelay: time.Second,
+	}
+	require.NoError(t, valid.validate())
+
+	tCases := []struct {
+		name   string
+		modify func(cfg *OutboxConfig)
+	}{
+		{name: "zero_batch_size", modify: func(cfg *OutboxConfig) { cfg.BatchSize = 0 }},
+		{name: "negative_batch_size", modify: func(cfg *OutboxConfig) { cfg.BatchSize = -1 }},
+		{name: "zero_poll_interval", modify: func(cfg *OutboxConfig) { cfg.PollInterval = 0 }},
+		{name: "backoff_below_poll_interval", modify: func(cfg *OutboxConfig) { cfg.MaxIdleBackoff = time.Millisecond }},
+		{name: "zero_max_attempts", modify: func(cfg *OutboxConfig) { cfg.MaxAttempts = 0 }},
+		{name: "negative_max_attempts", modify: func(cfg *OutboxConfig) { cfg.MaxAttempts = -1 }},
+		{name: "zero_retry_base_delay", modify: func(cfg *OutboxConfig) { cfg.RetryBaseDelay = 0 }},
+		{name: "negative_retry_base_delay", modify: func(cfg *OutboxConfig) { cfg.RetryBaseDelay = -time.Second }},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			cfg := valid
+			tCase.modify(&cfg)
+			require.Error(t, cfg.validate())
+		})
+	}
+
+	_, err := Load(writeConfig(t, "outbox:\n  batch_size: -1\n"))
+	require.Error(t, err)
+
+	_, err = Load(writeConfig(t, "outbox:\n  max_attempts: -1\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/rcrowley/go-metrics"
+)
+
+// restartDelay - пауза перед новой сессией группы после ошибки обработчика
+const restartDelay = time.Second
+
+// Consumer читает топики в составе consumer group и передаёт сообщения обработчику
+type Consumer struct {
+	log *slog.Logger
+
+	group    sarama.ConsumerGroup
+	topics   []string
+	handler  sarama.ConsumerGroupHandler
+	registry metrics.Registry
+}
+
+func New(
+	log *slog.Logger,
+	brokerList []string,
+	groupID string,
+	topics []string,
+	handler sarama.ConsumerGroupHandler,
+) (*Consumer, error) {
+	const op = "consumer.New"
+
+	cfg := sarama.NewConfig()
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	// оффсеты коммитятся вручную после коммита транзакции в базе
+	cfg.Consumer.Offsets.AutoCommit.Enable = false
+	cfg.Consumer.Return.Errors = true
+
+	group, err := sarama.NewConsumerGroup(brokerList, groupID, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Consumer{
+		log:      log,
+		group:    group,
+		topics:   topics,
+		handler:  handler,
+		registry: cfg.MetricRegistry,
+	}, nil
+}
+
+// MetricRegistry возвращает реестр, в котором sarama считает метрики группы
+func (c *Consumer) MetricRegistry() metrics.Registry {
+	return c.registry
+}
+
+// Run участвует в группе до отмены ctx. Consume возвращается при каждой
+// ребалансировке и при ошибке обработчика, после чего начинается новая сессия.
+func (c *Consumer) Run(ctx context.Context) error {
+	const op = "consumer.Run"
+
+	go func() {
+		for err := range c.group.Errors() {
+			c.log.Error(op, slog.String("consumer group error", err.Error()))
+		}
+	}()
+
+	for {
+		if err := c.group.Consume(ctx, c.topics, c.handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return nil
+			}
+
+			c.log.Error(op, slog.String("consume error", err.Error()))
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(restartDelay):
+			}
+		}
+
+		if ctx.Err() != nil {
+			c.log.Info(op, slog.String("status", "stopped"))
+			return nil
+		}
+	}
+}
+
+func (c *Consumer) Close() error {
+	return c.group.Close()
+}
@@ -0,0 +1,217 @@
+package consumer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// handleRetryDelay - пауза перед повторной обработкой сообщения после ошибки инфраструктуры
+const handleRetryDelay = time.Second
+
+const (
+	PaymentSucceeded = "PaymentSucceeded"
+	PaymentFailed    = "PaymentFailed"
+)
+
+// PaymentEvent - результат оплаты заказа от платёжного сервиса
+type PaymentEvent struct {
+	EventUUID uuid.UUID `json:"event_uuid"`
+	EventType string    `json:"event_type"`
+	OrderUUID uuid.UUID `json:"order_uuid"`
+	Reason    string    `json:"reason"`
+}
+
+// inboxConsumer - имя обработчика в таблице inbox
+const inboxConsumer = "order_service.payment"
+
+type inbox interface {
+	ProcessOnce(
+		ctx context.Context,
+		consumer string,
+		eventUUID uuid.UUID,
+		handler func(ctx context.Context, tx *sql.Tx) error,
+	) (bool, error)
+}
+
+type orderStatuses interface {
+	MarkPaidTx(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error
+	CancelTx(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error
+}
+
+// eventPublisher - best-effort уведомления, гарантированную доставку обеспечивает outbox
+type eventPublisher interface {
+	PublishStatusEvent(ctx context.Context, status *models.StatusStruct)
+}
+
+// PaymentHandler - обработчик sarama.ConsumerGroupHandler для событий оплаты.
+//
+// Событие применяется через inbox: изменение заказа и отметка об обработке
+// EventUUID коммитятся в одной транзакции, поэтому повторная доставка того же
+// события ничего не меняет. Оффсет сообщения коммитится только после коммита
+// в базе. Если обработка упала из-за инфраструктуры, оффсет не сдвигается, а
+// обработка повторяется, пока не пройдёт или пока партиция не уйдёт другому
+// участнику группы - тогда сообщение прочитает он.
+// Сообщения, которые невозможно применить (битый формат, неизвестный заказ,
+// недопустимый переход статуса), логируются и пропускаются, чтобы не
+// блокировать партицию.
+type PaymentHandler struct {
+	log        *slog.Logger
+	retryDelay time.Duration
+
+	inbox          inbox
+	orderStatuses  orderStatuses
+	eventPublisher eventPublisher
+}
+
+func NewPaymentHandler(
+	log *slog.Logger,
+	inbox inbox,
+	orderStatuses orderStatuses,
+	eventPublisher eventPublisher,
+) *PaymentHandler {
+	return &PaymentHandler{
+		log:            log,
+		retryDelay:     handleRetryDelay,
+		inbox:          inbox,
+		orderStatuses:  orderStatuses,
+		eventPublisher: eventPublisher,
+	}
+}
+
+func (h *PaymentHandler) Setup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *PaymentHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *PaymentHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			if !h.handleWithRetry(session, msg) {
+				return nil
+			}
+
+			session.MarkMessage(msg, "")
+			session.Commit()
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// handleWithRetry повторяет обработку сообщения, пока она не пройдёт. Возвращает
+// false, если сессия завершилась раньше - тогда оффсет сдвигать нельзя.
+func (h *PaymentHandler) handleWithRetry(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) bool {
+	const op = "consumer.payment.handleWithRetry"
+
+	for {
+		// начатая транзакция доводится до конца даже при ребалансировке
+		err := h.handle(context.WithoutCancel(session.Context()), msg)
+		if err == nil {
+			return true
+		}
+
+		h.log.Error(op,
+			slog.String("topic", msg.Topic),
+			slog.Int("partition", int(msg.Partition)),
+			slog.Int64("offset", msg.Offset),
+			slog.String("error", err.Error()),
+		)
+
+		select {
+		case <-session.Context().Done():
+			return false
+		case <-time.After(h.retryDelay):
+		}
+	}
+}
+
+// handle применяет событие оплаты к заказу. Ошибка возвращается только в том
+// случае, когда сообщение нужно прочитать повторно.
+func (h *PaymentHandler) handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	const op = "consumer.payment.handle"
+
+	var event PaymentEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		h.log.Warn(op, slog.String("skip malformed message", err.Error()), slog.Int64("offset", msg.Offset))
+		return nil
+	}
+
+	// событие без event_uuid попало бы в inbox под uuid.Nil, и все следующие
+	// такие же сообщения отбрасывались бы как дубликаты
+	if event.EventUUID == uuid.Nil || event.OrderUUID == uuid.Nil {
+		h.log.Warn(op, slog.String("skip malformed message", "missing event_uuid or order_uuid"), slog.Int64("offset", msg.Offset))
+		return nil
+	}
+
+	log := h.log.With(
+		slog.String("event_uuid", event.EventUUID.String()),
+		slog.String("order_uuid", event.OrderUUID.String()),
+		slog.String("event_type", event.EventType),
+	)
+
+	var (
+		to     models.OrderStatus
+		apply  func(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error
+		change = models.StatusChange{Actor: models.ActorPayment}
+	)
+
+	switch event.EventType {
+	case PaymentSucceeded:
+		to, apply = models.OrderStatusPaid, h.orderStatuses.MarkPaidTx
+		change.Reason = "payment succeeded"
+	case PaymentFailed:
+		to, apply = models.OrderStatusCanceled, h.orderStatuses.CancelTx
+		change.Reason = event.Reason
+		if change.Reason == "" {
+			change.Reason = "payment failed"
+		}
+	default:
+		log.Warn(op, slog.String("skip", "unknown event type"))
+		return nil
+	}
+
+	// неприменимое событие тоже отмечается в inbox: до ошибки проверки
+	// перехода репозиторий ничего не пишет, и транзакцию можно закоммитить
+	var skipped error
+	processed, err := h.inbox.ProcessOnce(ctx, inboxConsumer, event.EventUUID, func(ctx context.Context, tx *sql.Tx) error {
+		err := apply(ctx, tx, event.OrderUUID, change)
+		if errors.Is(err, internalErrors.ErrInvalidStatusTransition) || errors.Is(err, internalErrors.ErrOrderNotFound) {
+			skipped = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case !processed:
+		log.Info(op, slog.String("skip", "duplicate event"))
+	case skipped != nil:
+		log.Warn(op, slog.String("skip", skipped.Error()))
+	default:
+		log.Info(op, slog.String("status", "applied"))
+		h.eventPublisher.PublishStatusEvent(ctx, &models.StatusStruct{OrderUUID: event.OrderUUID, Status: to})
+	}
+
+	return nil
+}
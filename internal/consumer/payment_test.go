@@ -0,0 +1,325 @@
+package consumer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+const testTopic = "payment_topic"
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+
+	ctx context.Context
+
+	mu      sync.Mutex
+	marked  []int64
+	commits int
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.marked = append(s.marked, msg.Offset)
+}
+
+func (s *fakeSession) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commits++
+}
+
+func (s *fakeSession) commitCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commits
+}
+
+func (s *fakeSession) markedOffsets() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]int64(nil), s.marked...)
+}
+
+// fakeClaim отдаёт сообщения из партиции мок-консьюмера sarama
+type fakeClaim struct {
+	sarama.PartitionConsumer
+}
+
+func (c *fakeClaim) Topic() string        { return testTopic }
+func (c *fakeClaim) Partition() int32     { return 0 }
+func (c *fakeClaim) InitialOffset() int64 { return sarama.OffsetOldest }
+
+type statusCall struct {
+	method    string
+	orderUUID uuid.UUID
+	change    models.StatusChange
+}
+
+// fakeOrders возвращает ошибки из errs по очереди, затем nil
+type fakeOrders struct {
+	mu    sync.Mutex
+	errs  []error
+	calls []statusCall
+}
+
+func (f *fakeOrders) record(method string, orderUUID uuid.UUID, change models.StatusChange) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, statusCall{method: method, orderUUID: orderUUID, change: change})
+
+	if len(f.errs) == 0 {
+		return nil
+	}
+
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+
+	return err
+}
+
+func (f *fakeOrders) MarkPaidTx(_ context.Context, _ *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error {
+	return f.record("MarkPaid", orderUUID, change)
+}
+
+func (f *fakeOrders) CancelTx(_ context.Context, _ *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error {
+	return f.record("Cancel", orderUUID, change)
+}
+
+func (f *fakeOrders) recordedCalls() []statusCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]statusCall(nil), f.calls...)
+}
+
+// fakeInbox запоминает события, handler которых завершился без ошибки
+type fakeInbox struct {
+	mu        sync.Mutex
+	processed map[uuid.UUID]bool
+}
+
+func (f *fakeInbox) ProcessOnce(
+	ctx context.Context,
+	_ string,
+	eventUUID uuid.UUID,
+	handler func(ctx context.Context, tx *sql.Tx) error,
+) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.processed[eventUUID] {
+		return false, nil
+	}
+
+	if err := handler(ctx, nil); err != nil {
+		return false, err
+	}
+
+	if f.processed == nil {
+		f.processed = make(map[uuid.UUID]bool)
+	}
+	f.processed[eventUUID] = true
+
+	return true, nil
+}
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	statuses []models.StatusStruct
+}
+
+func (f *fakePublisher) PublishStatusEvent(_ context.Context, status *models.StatusStruct) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.statuses = append(f.statuses, *status)
+}
+
+func (f *fakePublisher) published() []models.StatusStruct {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]models.StatusStruct(nil), f.statuses...)
+}
+
+func paymentMessage(t *testing.T, offset int64, event PaymentEvent) *sarama.ConsumerMessage {
+	t.Helper()
+
+	value, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	return &sarama.ConsumerMessage{Topic: testTopic, Offset: offset, Value: value}
+}
+
+// startClaim запускает ConsumeClaim поверх мок-консьюмера sarama с заданными сообщениями
+func startClaim(
+	t *testing.T,
+	handler *PaymentHandler,
+	msgs ...*sarama.ConsumerMessage,
+) (*fakeSession, context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	consumer := mocks.NewConsumer(t, nil)
+	expectation := consumer.ExpectConsumePartition(testTopic, 0, sarama.OffsetOldest)
+	for _, msg := range msgs {
+		expectation.YieldMessage(msg)
+	}
+
+	partitionConsumer, err := consumer.ConsumePartition(testTopic, 0, sarama.OffsetOldest)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &fakeSession{ctx: ctx}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.ConsumeClaim(session, &fakeClaim{PartitionConsumer: partitionConsumer})
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		_ = partitionConsumer.Close()
+		require.NoError(t, consumer.Close())
+	})
+
+	return session, cancel, done
+}
+
+func newTestHandler(orders *fakeOrders, publisher *fakePublisher) *PaymentHandler {
+	handler := NewPaymentHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), &fakeInbox{}, orders, publisher)
+	handler.retryDelay = time.Millisecond
+
+	return handler
+}
+
+func TestPaymentHandlerAppliesEvents(t *testing.T) {
+	paidOrder, failedOrder, canceledOrder := uuid.New(), uuid.New(), uuid.New()
+
+	orders := &fakeOrders{errs: []error{
+		nil,
+		nil,
+		fmt.Errorf("services.order.MarkPaid: %w", &models.StatusTransitionError{
+			From: models.OrderStatusCanceled,
+			To:   models.OrderStatusPaid,
+		}),
+	}}
+
+	session, _, _ := startClaim(t, newTestHandler(orders, &fakePublisher{}),
+		paymentMessage(t, 0, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: paidOrder}),
+		paymentMessage(t, 1, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentFailed, OrderUUID: failedOrder, Reason: "insufficient funds"}),
+		paymentMessage(t, 2, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: canceledOrder}),
+		&sarama.ConsumerMessage{Topic: testTopic, Offset: 3, Value: []byte("not a json")},
+	)
+
+	require.Eventually(t, func() bool {
+		return len(session.markedOffsets()) == 4
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, []int64{0, 1, 2, 3}, session.markedOffsets())
+	require.Equal(t, 4, session.commitCount())
+	require.Equal(t, []statusCall{
+		{method: "MarkPaid", orderUUID: paidOrder, change: models.StatusChange{Actor: models.ActorPayment, Reason: "payment succeeded"}},
+		{method: "Cancel", orderUUID: failedOrder, change: models.StatusChange{Actor: models.ActorPayment, Reason: "insufficient funds"}},
+		{method: "MarkPaid", orderUUID: canceledOrder, change: models.StatusChange{Actor: models.ActorPayment, Reason: "payment succeeded"}},
+	}, orders.recordedCalls())
+}
+
+func TestPaymentHandlerRetriesBeforeCommit(t *testing.T) {
+	orderUUID := uuid.New()
+	dbErr := errors.New("connection refused")
+
+	orders := &fakeOrders{errs: []error{dbErr, dbErr}}
+
+	session, _, _ := startClaim(t, newTestHandler(orders, &fakePublisher{}),
+		paymentMessage(t, 0, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: orderUUID}),
+	)
+
+	require.Eventually(t, func() bool {
+		return len(session.markedOffsets()) == 1
+	}, time.Second, time.Millisecond)
+
+	require.Len(t, orders.recordedCalls(), 3)
+}
+
+func TestPaymentHandlerDoesNotCommitFailedMessage(t *testing.T) {
+	orders := &fakeOrders{}
+	for i := 0; i < 1000; i++ {
+		orders.errs = append(orders.errs, errors.New("connection refused"))
+	}
+
+	session, cancel, done := startClaim(t, newTestHandler(orders, &fakePublisher{}),
+		paymentMessage(t, 0, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: uuid.New()}),
+	)
+
+	require.Eventually(t, func() bool {
+		return len(orders.recordedCalls()) >= 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Empty(t, session.markedOffsets())
+	require.Zero(t, session.commitCount())
+}
+
+func TestPaymentHandlerSkipsRedeliveredEvent(t *testing.T) {
+	orderUUID := uuid.New()
+	event := PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: orderUUID}
+
+	orders, publisher := &fakeOrders{}, &fakePublisher{}
+
+	session, _, _ := startClaim(t, newTestHandler(orders, publisher),
+		paymentMessage(t, 0, event),
+		paymentMessage(t, 1, event),
+	)
+
+	require.Eventually(t, func() bool {
+		return len(session.markedOffsets()) == 2
+	}, time.Second, time.Millisecond)
+
+	require.Len(t, orders.recordedCalls(), 1)
+	require.Equal(t, []models.StatusStruct{{OrderUUID: orderUUID, Status: models.OrderStatusPaid}}, publisher.published())
+}
+
+func TestPaymentHandlerSkipsEventWithoutUUIDs(t *testing.T) {
+	orderUUID := uuid.New()
+	orders, publisher := &fakeOrders{}, &fakePublisher{}
+
+	session, _, _ := startClaim(t, newTestHandler(orders, publisher),
+		paymentMessage(t, 0, PaymentEvent{EventType: PaymentSucceeded, OrderUUID: uuid.New()}),
+		paymentMessage(t, 1, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded}),
+		paymentMessage(t, 2, PaymentEvent{EventType: PaymentSucceeded, OrderUUID: orderUUID}),
+		paymentMessage(t, 3, PaymentEvent{EventUUID: uuid.New(), EventType: PaymentSucceeded, OrderUUID: orderUUID}),
+	)
+
+	require.Eventually(t, func() bool {
+		return len(session.markedOffsets()) == 4
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, []statusCall{
+		{method: "MarkPaid", orderUUID: orderUUID, change: models.StatusChange{Actor: models.ActorPayment, Reason: "payment succeeded"}},
+	}, orders.recordedCalls())
+}
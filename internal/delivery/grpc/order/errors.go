@@ -0,0 +1,63 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type statusMapping struct {
+	err  error
+	code codes.Code
+}
+
+// domainErrors проверяются по порядку: точные причины недопустимого перехода
+// статуса стоят выше общей ErrInvalidStatusTransition
+var domainErrors = []statusMapping{
+	{internalErrors.ErrOrderNotFound, codes.NotFound},
+	{internalErrors.ErrOrderAlreadyCanceled, codes.FailedPrecondition},
+	{internalErrors.ErrOrderAlreadyDelivered, codes.FailedPrecondition},
+	{internalErrors.ErrOrderAlreadyPaid, codes.FailedPrecondition},
+	{internalErrors.ErrOrderNotPaid, codes.FailedPrecondition},
+	{internalErrors.ErrCancelOrderByStatus, codes.FailedPrecondition},
+	{internalErrors.ErrInvalidStatusTransition, codes.FailedPrecondition},
+	{internalErrors.ErrInvalidCurrency, codes.InvalidArgument},
+	{internalErrors.ErrMixedCurrency, codes.InvalidArgument},
+	{internalErrors.ErrAmountOverflow, codes.InvalidArgument},
+	{internalErrors.ErrIncorrectPoints, codes.InvalidArgument},
+	{internalErrors.ErrInsufficientPoints, codes.FailedPrecondition},
+	{internalErrors.ErrPromoCodeNotFound, codes.NotFound},
+	{internalErrors.ErrPromoCodeInactive, codes.FailedPrecondition},
+	{internalErrors.ErrPromoCodeNotApplicable, codes.FailedPrecondition},
+	{internalErrors.ErrPromoCodeLimitReached, codes.FailedPrecondition},
+}
+
+// toStatus переводит ошибку сервиса в статус gRPC. Клиент получает текст
+// доменной ошибки без обёрток, исходная ошибка попадает только в лог.
+func toStatus(ctx context.Context, log *slog.Logger, op string, err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, context.Canceled.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, context.DeadlineExceeded.Error())
+	}
+
+	for _, m := range domainErrors {
+		if errors.Is(err, m.err) {
+			log.WarnContext(ctx, op, slog.String("code", m.code.String()), slog.String("error", err.Error()))
+			return status.Error(m.code, m.err.Error())
+		}
+	}
+
+	log.ErrorContext(ctx, op, slog.String("code", codes.Internal.String()), slog.String("error", err.Error()))
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
@@ -0,0 +1,153 @@
+package order
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/grpc/pb/orderservicev1"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+var (
+	errInvalidOrderUUID = errors.New("invalid order_uuid")
+	errInvalidStatus    = errors.New("invalid status")
+	errEmptyOrderUUIDs  = errors.New("no order uuids passed")
+	errInvalidCreatedAt = errors.New("created_from should be before created_to")
+	errInvalidCursor    = errors.New("invalid cursor")
+	errInvalidLimit     = errors.New("invalid limit")
+)
+
+// createOrderToModel проверяет запрос и собирает по нему заказ, проверки
+// общие с HTTP API
+func createOrderToModel(req *orderservicev1.CreateOrderRequest) (models.Order, error) {
+	paymentType, err := paymentTypeToModel(req.GetPaymentType())
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	lines := make([]models.CreateOrderLine, 0, len(req.GetProducts()))
+	for _, line := range req.GetProducts() {
+		lines = append(lines, models.CreateOrderLine{
+			ProductUUID: line.GetProductUuid(),
+			Quantity:    line.GetQuantity(),
+			UnitPrice:   line.GetUnitPrice(),
+			Currency:    line.GetCurrency(),
+		})
+	}
+
+	return models.CreateOrderRequest{
+		UserUUID:    req.GetUserUuid(),
+		PaymentType: paymentType,
+		Products:    lines,
+		WithPoints:  int(req.GetWithPoints()),
+		PromoCode:   req.GetPromoCode(),
+	}.Order()
+}
+
+func orderUUIDToModel(orderUUID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(orderUUID)
+	if err != nil {
+		return uuid.Nil, errInvalidOrderUUID
+	}
+
+	return id, nil
+}
+
+func orderUUIDsToModel(orderUUIDs []string) ([]uuid.UUID, error) {
+	if len(orderUUIDs) == 0 {
+		return nil, errEmptyOrderUUIDs
+	}
+
+	result := make([]uuid.UUID, 0, len(orderUUIDs))
+	for _, orderUUID := range orderUUIDs {
+		id, err := orderUUIDToModel(orderUUID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+func cancelOrderToModel(req *orderservicev1.CancelOrderRequest) (uuid.UUID, models.StatusChange, error) {
+	orderUUID, err := orderUUIDToModel(req.GetOrderUuid())
+	if err != nil {
+		return uuid.Nil, models.StatusChange{}, err
+	}
+
+	actor, err := models.ClientActor(req.GetActor())
+	if err != nil {
+		return uuid.Nil, models.StatusChange{}, err
+	}
+
+	return orderUUID, models.StatusChange{Actor: actor, Reason: req.GetReason()}, nil
+}
+
+func listOrdersToModel(req *orderservicev1.ListOrdersRequest) (models.OrderFilter, error) {
+	filter := models.OrderFilter{Limit: defaultListLimit}
+
+	userUUID, err := uuid.Parse(req.GetUserUuid())
+	if err != nil {
+		return filter, internalErrors.ErrInvalidUserUUID
+	}
+	filter.UserUUID = userUUID
+
+	for _, s := range req.GetStatuses() {
+		st := models.OrderStatus(s)
+		if st < models.OrderStatusCreated || st > models.OrderStatusCanceled {
+			return filter, errInvalidStatus
+		}
+		filter.Statuses = append(filter.Statuses, st)
+	}
+
+	if filter.PaymentType, err = paymentTypeToModel(req.GetPaymentType()); err != nil {
+		return filter, err
+	}
+
+	if req.GetCreatedFrom() != nil {
+		filter.CreatedFrom = req.GetCreatedFrom().AsTime()
+	}
+	if req.GetCreatedTo() != nil {
+		filter.CreatedTo = req.GetCreatedTo().AsTime()
+	}
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return filter, errInvalidCreatedAt
+	}
+
+	if req.GetCursor() != "" {
+		if filter.After, err = models.ParseOrderCursor(req.GetCursor()); err != nil {
+			return filter, errInvalidCursor
+		}
+	}
+
+	if req.GetLimit() != 0 {
+		if req.GetLimit() < 0 || req.GetLimit() > maxListLimit {
+			return filter, errInvalidLimit
+		}
+		filter.Limit = int(req.GetLimit())
+	}
+
+	return filter, nil
+}
+
+// paymentTypeToModel возвращает UndefinedType для незаданного типа оплаты
+func paymentTypeToModel(paymentType orderv1.PaymentType) (models.PaymentType, error) {
+	switch paymentType {
+	case orderv1.PaymentType_PAYMENT_TYPE_UNSPECIFIED:
+		return models.UndefinedType, nil
+	case orderv1.PaymentType_PAYMENT_TYPE_CARD:
+		return models.Card, nil
+	case orderv1.PaymentType_PAYMENT_TYPE_POINTS:
+		return models.Points, nil
+	default:
+		return models.UndefinedType, internalErrors.ErrInvalidPaymentType
+	}
+}
@@ -0,0 +1,140 @@
+// Package order реализует gRPC-сервис orderservice.v1.OrderService поверх
+// тех же сервисов заказов, что и HTTP API.
+package order
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/grpc/pb/orderservicev1"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+)
+
+type orderCreator interface {
+	Create(ctx context.Context, order *models.Order) (string, error)
+}
+
+type orderGetter interface {
+	OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) ([]models.Order, error)
+	OrderByUUID(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+	UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error)
+}
+
+type orderCanceler interface {
+	Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+}
+
+type Server struct {
+	orderservicev1.UnimplementedOrderServiceServer
+
+	log *slog.Logger
+
+	orderCreator  orderCreator
+	orderGetter   orderGetter
+	orderCanceler orderCanceler
+}
+
+func NewServer(log *slog.Logger, orderCreator orderCreator, orderGetter orderGetter, orderCanceler orderCanceler) *Server {
+	return &Server{
+		log:           log,
+		orderCreator:  orderCreator,
+		orderGetter:   orderGetter,
+		orderCanceler: orderCanceler,
+	}
+}
+
+func (s *Server) CreateOrder(ctx context.Context, req *orderservicev1.CreateOrderRequest) (*orderservicev1.CreateOrderResponse, error) {
+	const op = "delivery.grpc.order.CreateOrder"
+
+	order, err := createOrderToModel(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	orderUUID, err := s.orderCreator.Create(ctx, &order)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, op, err)
+	}
+
+	return &orderservicev1.CreateOrderResponse{OrderUuid: orderUUID}, nil
+}
+
+func (s *Server) GetOrder(ctx context.Context, req *orderservicev1.GetOrderRequest) (*orderservicev1.GetOrderResponse, error) {
+	const op = "delivery.grpc.order.GetOrder"
+
+	orderUUID, err := orderUUIDToModel(req.GetOrderUuid())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	order, err := s.orderGetter.OrderByUUID(ctx, orderUUID)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, op, err)
+	}
+
+	return &orderservicev1.GetOrderResponse{Order: encoding.OrderProto(*order)}, nil
+}
+
+func (s *Server) BatchGetOrders(ctx context.Context, req *orderservicev1.BatchGetOrdersRequest) (*orderservicev1.BatchGetOrdersResponse, error) {
+	const op = "delivery.grpc.order.BatchGetOrders"
+
+	orderUUIDs, err := orderUUIDsToModel(req.GetOrderUuids())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	orders, err := s.orderGetter.OrdersByUUIDs(ctx, orderUUIDs)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, op, err)
+	}
+
+	return &orderservicev1.BatchGetOrdersResponse{Orders: ordersProto(orders)}, nil
+}
+
+func (s *Server) CancelOrder(ctx context.Context, req *orderservicev1.CancelOrderRequest) (*orderservicev1.CancelOrderResponse, error) {
+	const op = "delivery.grpc.order.CancelOrder"
+
+	orderUUID, change, err := cancelOrderToModel(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	if err = s.orderCanceler.Cancel(ctx, orderUUID, change); err != nil {
+		return nil, toStatus(ctx, s.log, op, err)
+	}
+
+	return &orderservicev1.CancelOrderResponse{}, nil
+}
+
+func (s *Server) ListOrders(ctx context.Context, req *orderservicev1.ListOrdersRequest) (*orderservicev1.ListOrdersResponse, error) {
+	const op = "delivery.grpc.order.ListOrders"
+
+	filter, err := listOrdersToModel(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	page, err := s.orderGetter.UserOrders(ctx, filter)
+	if err != nil {
+		return nil, toStatus(ctx, s.log, op, err)
+	}
+
+	resp := &orderservicev1.ListOrdersResponse{Orders: ordersProto(page.Orders)}
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+
+	return resp, nil
+}
+
+func ordersProto(orders []models.Order) []*orderv1.Order {
+	result := make([]*orderv1.Order, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, encoding.OrderProto(order))
+	}
+
+	return result
+}
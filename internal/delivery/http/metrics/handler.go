@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Handler отдаёт снимок метрик go-metrics в JSON. registries - реестры по
+// компонентам, например метрики sarama и счётчики уведомлений продюсера:
+// ответ содержит объект на каждый компонент.
+func Handler(log *slog.Logger, registries map[string]metrics.Registry) http.HandlerFunc {
+	const op = "delivery.http.metrics"
+
+	return func(w http.ResponseWriter, _ *http.Request) {
+		snapshot := make(map[string]map[string]map[string]any, len(registries))
+		for name, registry := range registries {
+			snapshot[name] = registry.GetAll()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			log.Error(op, slog.String("failed to encode response", err.Error()))
+		}
+	}
+}
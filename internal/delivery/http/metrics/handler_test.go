@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("notification-failed-for-topic-orders", registry).Inc(2)
+
+	handler := Handler(slog.New(slog.NewTextHandler(io.Discard, nil)), map[string]metrics.Registry{
+		"notifications": registry,
+		"consumer":      metrics.NewRegistry(),
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var snapshot map[string]map[string]map[string]float64
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &snapshot))
+	require.Equal(t, float64(2), snapshot["notifications"]["notification-failed-for-topic-orders"]["count"])
+	require.Empty(t, snapshot["consumer"])
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed проставляется на ответ, взятый из хранилища
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	idempotencyPollInterval = 50 * time.Millisecond
+)
+
+type idempotencyStore interface {
+	Reserve(
+		ctx context.Context,
+		key string,
+		owner uuid.UUID,
+		requestHash string,
+		ttl time.Duration,
+		lockTimeout time.Duration,
+	) (*models.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, owner uuid.UUID, response models.IdempotentResponse) error
+	Release(ctx context.Context, key string, owner uuid.UUID) error
+	PurgeExpired(ctx context.Context, batchSize int) (int64, error)
+}
+
+// Idempotency дедуплицирует запросы по заголовку Idempotency-Key.
+//
+// Первый запрос с ключом выполняется, и его успешный ответ сохраняется. Повтор
+// с тем же ключом и телом получает сохранённый ответ, с тем же ключом и другим
+// телом - 422. Пока первый запрос выполняется, повторы ждут его до WaitTimeout,
+// а затем получают 409. Ответы с ошибкой не сохраняются: ключ освобождается,
+// и запрос можно повторить. Сохранённый ответ отдаётся как application/json.
+//
+// Каждый запрос занимает ключ под своим owner. Если запрос выполнялся дольше
+// LockTimeout и ключ перехватил повтор, его ответ не сохраняется и ключ не
+// освобождается: хранилище принадлежит уже повтору.
+type Idempotency struct {
+	log   *slog.Logger
+	store idempotencyStore
+	cfg   config.IdempotencyConfig
+
+	pollInterval time.Duration
+}
+
+func NewIdempotency(log *slog.Logger, store idempotencyStore, cfg config.IdempotencyConfig) *Idempotency {
+	return &Idempotency{
+		log:          log,
+		store:        store,
+		cfg:          cfg,
+		pollInterval: idempotencyPollInterval,
+	}
+}
+
+func (i *Idempotency) Handler(next http.Handler) http.Handler {
+	const op = "delivery.http.middleware.Idempotency"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderIdempotencyKey)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			problem.BadRequest(w, r, i.log, op, fmt.Errorf("%s is too long", HeaderIdempotencyKey))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			problem.BadRequest(w, r, i.log, op, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		log := i.log.With(slog.String("idempotency_key", key))
+		owner := uuid.New()
+
+		stored, err := i.reserve(r.Context(), key, owner, requestHash(r.Method, r.URL.Path, body))
+		if err != nil {
+			problem.Error(w, r, log, op, err)
+			return
+		}
+
+		if stored != nil {
+			log.Info(op, slog.String("status", "replayed"))
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(HeaderIdempotentReplayed, "true")
+			w.WriteHeader(stored.StatusCode)
+			_, _ = w.Write(stored.Body)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// ответ уже ушёл клиенту, результат нужно записать даже если он отключился
+		ctx := context.WithoutCancel(r.Context())
+
+		if recorder.statusCode >= http.StatusOK && recorder.statusCode < http.StatusMultipleChoices {
+			response := models.IdempotentResponse{StatusCode: recorder.statusCode, Body: recorder.body.Bytes()}
+			err = i.store.Complete(ctx, key, owner, response)
+			switch {
+			case errors.Is(err, internalErrors.ErrIdempotencyKeyLockLost):
+				log.Warn(op, slog.String("response not saved", err.Error()))
+			case err != nil:
+				log.Error(op, slog.String("failed to save response", err.Error()))
+			}
+			return
+		}
+
+		if err = i.store.Release(ctx, key, owner); err != nil {
+			log.Error(op, slog.String("failed to release key", err.Error()))
+		}
+	})
+}
+
+// RunPurge удаляет истёкшие ключи сразу и затем каждые PurgeInterval, пока не
+// будет отменён ctx. Нулевой PurgeInterval отключает очистку.
+func (i *Idempotency) RunPurge(ctx context.Context) {
+	const op = "delivery.http.middleware.Idempotency.RunPurge"
+
+	if i.cfg.PurgeInterval <= 0 {
+		i.log.Info(op, slog.String("status", "disabled"))
+		return
+	}
+
+	ticker := time.NewTicker(i.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := i.store.PurgeExpired(ctx, i.cfg.PurgeBatchSize)
+		if err != nil && ctx.Err() == nil {
+			i.log.Error(op, slog.String("purge error", err.Error()))
+		}
+		if purged > 0 {
+			i.log.Info(op, slog.Int64("purged", purged))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reserve занимает ключ, ожидая завершения конкурентного запроса не дольше WaitTimeout
+func (i *Idempotency) reserve(ctx context.Context, key string, owner uuid.UUID, hash string) (*models.IdempotentResponse, error) {
+	deadline := time.Now().Add(i.cfg.WaitTimeout)
+
+	for {
+		stored, err := i.store.Reserve(ctx, key, owner, hash, i.cfg.TTL, i.cfg.LockTimeout)
+		if !errors.Is(err, internalErrors.ErrIdempotencyKeyInProgress) || time.Now().After(deadline) {
+			return stored, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(i.pollInterval):
+		}
+	}
+}
+
+// requestHash считает хэш запроса. JSON-тело приводится к каноничному виду,
+// чтобы порядок ключей и пробелы не делали одинаковые запросы разными.
+func requestHash(method, path string, body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err == nil && !decoder.More() {
+		if canonical, err := json.Marshal(value); err == nil {
+			body = canonical
+		}
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder пишет ответ клиенту и запоминает его для сохранения
+type responseRecorder struct {
+	http.ResponseWriter
+
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if !rr.wroteHeader {
+		rr.statusCode = statusCode
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(b)
+
+	return rr.ResponseWriter.Write(b)
+}
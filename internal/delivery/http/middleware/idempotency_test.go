@@ -0,0 +1,369 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+type storedKey struct {
+	owner    uuid.UUID
+	hash     string
+	response *models.IdempotentResponse
+	expired  bool
+}
+
+// memoryStore повторяет семантику repository.IdempotencyRepository без истечения
+// ключей по времени: перехват ключа тест делает сам через takeOver, а
+// истечение - через expire
+type memoryStore struct {
+	mu     sync.Mutex
+	keys   map[string]*storedKey
+	purges int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{keys: make(map[string]*storedKey)}
+}
+
+func (s *memoryStore) Reserve(
+	_ context.Context,
+	key string,
+	owner uuid.UUID,
+	hash string,
+	_, _ time.Duration,
+) (*models.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.keys[key]
+	switch {
+	case !ok:
+		s.keys[key] = &storedKey{owner: owner, hash: hash}
+		return nil, nil
+	case stored.hash != hash:
+		return nil, internalErrors.ErrIdempotencyKeyReused
+	case stored.response == nil:
+		return nil, internalErrors.ErrIdempotencyKeyInProgress
+	default:
+		return stored.response, nil
+	}
+}
+
+func (s *memoryStore) Complete(_ context.Context, key string, owner uuid.UUID, response models.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.keys[key]
+	if !ok || stored.owner != owner || stored.response != nil {
+		return internalErrors.ErrIdempotencyKeyLockLost
+	}
+	stored.response = &response
+
+	return nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string, owner uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.keys[key]; ok && stored.owner == owner && stored.response == nil {
+		delete(s.keys, key)
+	}
+
+	return nil
+}
+
+func (s *memoryStore) PurgeExpired(context.Context, int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purges++
+
+	var purged int64
+	for key, stored := range s.keys {
+		if stored.expired {
+			delete(s.keys, key)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+func (s *memoryStore) expire(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key].expired = true
+}
+
+func (s *memoryStore) purgeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.purges
+}
+
+// takeOver отдаёт ключ другому запросу, как Reserve после LockTimeout
+func (s *memoryStore) takeOver(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key].owner = uuid.New()
+}
+
+func (s *memoryStore) response(key string) *models.IdempotentResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.keys[key]; ok {
+		return stored.response
+	}
+
+	return nil
+}
+
+// createHandler имитирует POST /order: на каждый вызов создаёт новый заказ
+type createHandler struct {
+	calls   atomic.Int32
+	status  int
+	release chan struct{}
+}
+
+func (h *createHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.calls.Add(1)
+
+	if h.release != nil {
+		<-h.release
+	}
+
+	if h.status != 0 && h.status != http.StatusOK {
+		http.Error(w, "failed", h.status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"order_uuid": uuid.NewString()})
+}
+
+func newTestServer(store *memoryStore, handler http.Handler, waitTimeout time.Duration) http.Handler {
+	idempotency := NewIdempotency(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		store,
+		config.IdempotencyConfig{TTL: time.Hour, WaitTimeout: waitTimeout, LockTimeout: time.Minute},
+	)
+	idempotency.pollInterval = time.Millisecond
+
+	return idempotency.Handler(handler)
+}
+
+func doRequest(server http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/order/", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(HeaderIdempotencyKey, key)
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	return w
+}
+
+func orderUUID(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	return resp["order_uuid"]
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	handler := &createHandler{}
+	server := newTestServer(newMemoryStore(), handler, time.Second)
+
+	first := doRequest(server, "key-1", `{"user_uuid": "u", "payment_type": "card"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+
+	// тот же JSON с другим порядком ключей и пробелами - тот же запрос
+	replay := doRequest(server, "key-1", `{"payment_type":"card","user_uuid":"u"}`)
+	require.Equal(t, http.StatusOK, replay.Code)
+	require.Equal(t, "true", replay.Header().Get(HeaderIdempotentReplayed))
+	require.Equal(t, orderUUID(t, first), orderUUID(t, replay))
+
+	require.Equal(t, int32(1), handler.calls.Load())
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	handler := &createHandler{}
+	server := newTestServer(newMemoryStore(), handler, time.Second)
+
+	require.Equal(t, http.StatusOK, doRequest(server, "key-1", `{"with_points": 1}`).Code)
+	require.Equal(t, http.StatusUnprocessableEntity, doRequest(server, "key-1", `{"with_points": 2}`).Code)
+
+	require.Equal(t, int32(1), handler.calls.Load())
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	handler := &createHandler{}
+	server := newTestServer(newMemoryStore(), handler, time.Second)
+
+	first := doRequest(server, "", `{}`)
+	second := doRequest(server, "", `{}`)
+	require.NotEqual(t, orderUUID(t, first), orderUUID(t, second))
+
+	require.Equal(t, int32(2), handler.calls.Load())
+}
+
+func TestIdempotencyFailedRequestReleasesKey(t *testing.T) {
+	handler := &createHandler{status: http.StatusInternalServerError}
+	server := newTestServer(newMemoryStore(), handler, time.Second)
+
+	require.Equal(t, http.StatusInternalServerError, doRequest(server, "key-1", `{}`).Code)
+
+	handler.status = http.StatusOK
+	require.Equal(t, http.StatusOK, doRequest(server, "key-1", `{}`).Code)
+
+	require.Equal(t, int32(2), handler.calls.Load())
+}
+
+func TestIdempotencyConcurrentRequestWaitsForFirst(t *testing.T) {
+	handler := &createHandler{release: make(chan struct{})}
+	server := newTestServer(newMemoryStore(), handler, time.Second)
+
+	responses := make(chan *httptest.ResponseRecorder, 2)
+	go func() {
+		responses <- doRequest(server, "key-1", `{}`)
+	}()
+
+	require.Eventually(t, func() bool {
+		return handler.calls.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	go func() {
+		responses <- doRequest(server, "key-1", `{}`)
+	}()
+
+	// второй запрос ждёт, пока первый не завершится
+	time.Sleep(20 * time.Millisecond)
+	close(handler.release)
+
+	first, second := <-responses, <-responses
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, http.StatusOK, second.Code)
+	require.Equal(t, orderUUID(t, first), orderUUID(t, second))
+
+	require.Equal(t, int32(1), handler.calls.Load())
+}
+
+func TestIdempotencyConcurrentRequestRejectedAfterWaitTimeout(t *testing.T) {
+	handler := &createHandler{release: make(chan struct{})}
+	defer close(handler.release)
+
+	server := newTestServer(newMemoryStore(), handler, 20*time.Millisecond)
+
+	go doRequest(server, "key-1", `{}`)
+
+	require.Eventually(t, func() bool {
+		return handler.calls.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	require.Equal(t, http.StatusConflict, doRequest(server, "key-1", `{}`).Code)
+}
+
+func TestIdempotencyTakenOverKeyKeepsNewOwner(t *testing.T) {
+	tCases := []struct {
+		name   string
+		status int
+	}{
+		{name: "success_not_saved", status: http.StatusOK},
+		{name: "failure_not_released", status: http.StatusInternalServerError},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			store := newMemoryStore()
+			handler := &createHandler{status: tCase.status, release: make(chan struct{})}
+			server := newTestServer(store, handler, time.Second)
+
+			done := make(chan *httptest.ResponseRecorder)
+			go func() {
+				done <- doRequest(server, "key-1", `{}`)
+			}()
+
+			require.Eventually(t, func() bool {
+				return handler.calls.Load() == 1
+			}, time.Second, time.Millisecond)
+
+			// запрос завис дольше LockTimeout, и ключ занял повтор
+			store.takeOver("key-1")
+			close(handler.release)
+			require.Equal(t, tCase.status, (<-done).Code)
+
+			require.Nil(t, store.response("key-1"))
+			require.Equal(t, http.StatusConflict, doRequest(newTestServer(store, handler, 0), "key-1", `{}`).Code)
+		})
+	}
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	server := newTestServer(newMemoryStore(), &createHandler{}, time.Second)
+
+	require.Equal(t, http.StatusBadRequest, doRequest(server, strings.Repeat("k", 256), `{}`).Code)
+}
+
+func TestIdempotencyRunPurge(t *testing.T) {
+	store := newMemoryStore()
+	server := newTestServer(store, &createHandler{}, time.Second)
+
+	require.Equal(t, http.StatusOK, doRequest(server, "expired", `{"user_uuid": "1"}`).Code)
+	require.Equal(t, http.StatusOK, doRequest(server, "live", `{"user_uuid": "1"}`).Code)
+	store.expire("expired")
+
+	idempotency := NewIdempotency(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		store,
+		config.IdempotencyConfig{PurgeInterval: time.Millisecond, PurgeBatchSize: 100},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		idempotency.RunPurge(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		return store.purgeCount() >= 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	require.Nil(t, store.response("expired"))
+	require.NotNil(t, store.response("live"))
+}
+
+func TestIdempotencyRunPurgeDisabled(t *testing.T) {
+	store := newMemoryStore()
+	idempotency := NewIdempotency(slog.New(slog.NewTextHandler(io.Discard, nil)), store, config.IdempotencyConfig{})
+
+	// с нулевым PurgeInterval RunPurge сразу возвращает управление
+	idempotency.RunPurge(context.Background())
+	require.Zero(t, store.purgeCount())
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+)
+
+// ResponseErrorFunc получает ответы, которые не соответствуют OpenAPI-описанию
+type ResponseErrorFunc func(r *http.Request, status int, err error)
+
+type OpenAPIOption func(v *OpenAPI)
+
+// WithResponseValidation включает проверку ответов. Ответ клиенту не меняется,
+// о расхождении с описанием сообщается в report. Нужна в тестах: в проде
+// она буферизует каждый ответ.
+func WithResponseValidation(report ResponseErrorFunc) OpenAPIOption {
+	return func(v *OpenAPI) {
+		v.reportResponse = report
+	}
+}
+
+// OpenAPI проверяет запросы по OpenAPI-описанию API. Невалидный запрос получает
+// 400 до того, как попадёт в хендлер. Маршруты, которых нет в описании,
+// пропускаются без проверки: ответ на них даёт роутер.
+type OpenAPI struct {
+	log     *slog.Logger
+	router  routers.Router
+	options *openapi3filter.Options
+
+	reportResponse ResponseErrorFunc
+}
+
+func NewOpenAPI(log *slog.Logger, doc *openapi3.T, opts ...OpenAPIOption) (*OpenAPI, error) {
+	const op = "delivery.http.middleware.NewOpenAPI"
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults:   true,
+		IncludeResponseStatus: true,
+	}
+	options.WithCustomSchemaErrorFunc(schemaErrorMessage)
+
+	v := &OpenAPI{
+		log:     log,
+		router:  router,
+		options: options,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v, nil
+}
+
+func (v *OpenAPI) Handler(next http.Handler) http.Handler {
+	const op = "delivery.http.middleware.OpenAPI"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(routedRequest(r))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// API принимает только JSON, запрос с телом без Content-Type хендлеры
+		// всегда разбирали как JSON
+		if r.Header.Get("Content-Type") == "" && r.ContentLength != 0 {
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    v.options,
+		}
+		if err = openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			problem.BadRequest(w, r, v.log, op, err)
+			return
+		}
+
+		if v.reportResponse == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		response := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.statusCode,
+			Header:                 recorder.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                v.options,
+		}
+		if err = openapi3filter.ValidateResponse(r.Context(), response); err != nil {
+			v.reportResponse(r, recorder.statusCode, err)
+		}
+	})
+}
+
+// routedRequest убирает завершающий слэш: chi отдаёт /order и /order/ одному
+// хендлеру, а в описании путь записан без слэша
+func routedRequest(r *http.Request) *http.Request {
+	if len(r.URL.Path) <= 1 || !strings.HasSuffix(r.URL.Path, "/") {
+		return r
+	}
+
+	routed := r.Clone(r.Context())
+	routed.URL.Path = strings.TrimSuffix(r.URL.Path, "/")
+
+	return routed
+}
+
+// schemaErrorMessage сокращает ошибку схемы до пути и причины, без дампа схемы
+func schemaErrorMessage(err *openapi3.SchemaError) string {
+	if err.Reason == "" {
+		return ""
+	}
+
+	if pointer := err.JSONPointer(); len(pointer) > 0 {
+		return fmt.Sprintf("/%s: %s", strings.Join(pointer, "/"), err.Reason)
+	}
+
+	return err.Reason
+}
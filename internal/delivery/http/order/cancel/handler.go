@@ -6,11 +6,20 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
 type orderCancaler interface {
-	Cancel(ctx context.Context, orderUUID uuid.UUID) error
+	Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+	CancelLines(
+		ctx context.Context,
+		orderUUID uuid.UUID,
+		productUUIDs []uuid.UUID,
+		change models.StatusChange,
+	) (*models.LinesCancellation, error)
 }
 
 type Handler struct {
@@ -26,25 +35,23 @@ func NewHandler(log *slog.Logger, orderCancaler orderCancaler) *Handler {
 }
 
 func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.cancel_order.cancel"
 	var request CancelOrderRequest
 
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		h.log.Error("failed to decode request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.BadRequest(w, r, h.log, op, err)
 		return
 	}
 
 	if err = request.validate(); err != nil {
-		h.log.Error("failed to validate request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.BadRequest(w, r, h.log, op, err)
 		return
 	}
 
-	orderUUID := request.toServiceRepresentation()
-	if err = h.orderCancaler.Cancel(r.Context(), orderUUID); err != nil {
-		h.log.Error("failed to cancel order: ", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	orderUUID, change := request.toServiceRepresentation()
+	if err = h.orderCancaler.Cancel(r.Context(), orderUUID, change); err != nil {
+		problem.Error(w, r, h.log, op, err)
 		return
 	}
 
@@ -56,8 +63,37 @@ func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
 			"message": "order canceled",
 		},
 	); err != nil {
-		h.log.Error("failed to encode response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
+}
+
+// CancelLines отменяет отдельные строки заказа и отдаёт сумму возврата
+func (h *Handler) CancelLines(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.cancel_order.cancel_lines"
+	var request CancelLinesRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
+		return
+	}
+	request.OrderUUID = chi.URLParam(r, "uuid")
+
+	if err := request.validate(); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
+		return
+	}
+
+	orderUUID, productUUIDs, change := request.toServiceRepresentation()
+	cancellation, err := h.orderCancaler.CancelLines(r.Context(), orderUUID, productUUIDs, change)
+	if err != nil {
+		problem.Error(w, r, h.log, op, err)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = json.NewEncoder(w).Encode(cancellation); err != nil {
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
 }
@@ -4,15 +4,20 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
 var (
-	errEmptyOrderUUID   = errors.New("order_uuid should not be empty")
-	errInvalidOrderUUID = errors.New("invalid order_uuid")
+	errEmptyOrderUUID     = errors.New("order_uuid should not be empty")
+	errInvalidOrderUUID   = errors.New("invalid order_uuid")
+	errEmptyProductUUIDs  = errors.New("product_uuids should not be empty")
+	errInvalidProductUUID = errors.New("invalid product_uuid")
 )
 
 type CancelOrderRequest struct {
 	OrderUUID string `json:"order_uuid"`
+	Reason    string `json:"reason"`
+	Actor     string `json:"actor"`
 }
 
 func (r *CancelOrderRequest) validate() error {
@@ -24,9 +29,58 @@ func (r *CancelOrderRequest) validate() error {
 		return fmt.Errorf("%w: %s", errInvalidOrderUUID, err.Error())
 	}
 
+	if _, err := models.ClientActor(r.Actor); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-func (r *CancelOrderRequest) toServiceRepresentation() uuid.UUID {
-	return uuid.MustParse(r.OrderUUID)
+func (r *CancelOrderRequest) toServiceRepresentation() (uuid.UUID, models.StatusChange) {
+	actor, _ := models.ClientActor(r.Actor)
+
+	return uuid.MustParse(r.OrderUUID), models.StatusChange{Actor: actor, Reason: r.Reason}
+}
+
+// CancelLinesRequest - отмена строк заказа: OrderUUID берётся из пути,
+// строки задаются uuid товаров
+type CancelLinesRequest struct {
+	OrderUUID    string   `json:"-"`
+	ProductUUIDs []string `json:"product_uuids"`
+	Reason       string   `json:"reason"`
+	Actor        string   `json:"actor"`
+}
+
+func (r *CancelLinesRequest) validate() error {
+	if _, err := uuid.Parse(r.OrderUUID); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidOrderUUID, err.Error())
+	}
+
+	if len(r.ProductUUIDs) == 0 {
+		return errEmptyProductUUIDs
+	}
+
+	for _, productUUID := range r.ProductUUIDs {
+		if _, err := uuid.Parse(productUUID); err != nil {
+			return fmt.Errorf("%w: %s", errInvalidProductUUID, err.Error())
+		}
+	}
+
+	if _, err := models.ClientActor(r.Actor); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CancelLinesRequest) toServiceRepresentation() (uuid.UUID, []uuid.UUID, models.StatusChange) {
+	productUUIDs := make([]uuid.UUID, 0, len(r.ProductUUIDs))
+	for _, productUUID := range r.ProductUUIDs {
+		productUUIDs = append(productUUIDs, uuid.MustParse(productUUID))
+	}
+
+	orderUUID, change := (&CancelOrderRequest{OrderUUID: r.OrderUUID, Reason: r.Reason, Actor: r.Actor}).
+		toServiceRepresentation()
+
+	return orderUUID, productUUIDs, change
 }
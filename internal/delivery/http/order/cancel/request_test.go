@@ -0,0 +1,52 @@
+package cancel
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestCancelOrderRequestActor(t *testing.T) {
+	orderUUID := uuid.NewString()
+
+	tCases := []struct {
+		name     string
+		actor    string
+		expActor string
+		expErr   error
+	}{
+		{name: "default_customer", actor: "", expActor: models.ActorCustomer},
+		{name: "customer", actor: models.ActorCustomer, expActor: models.ActorCustomer},
+		{name: "system_reserved", actor: models.ActorSystem, expErr: internalErrors.ErrInvalidActor},
+		{name: "payment_reserved", actor: models.ActorPayment, expErr: internalErrors.ErrInvalidActor},
+		{name: "unknown", actor: "admin", expErr: internalErrors.ErrInvalidActor},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			request := CancelOrderRequest{OrderUUID: orderUUID, Actor: tCase.actor}
+
+			err := request.validate()
+			if tCase.expErr != nil {
+				require.ErrorIs(t, err, tCase.expErr)
+				return
+			}
+			require.NoError(t, err)
+
+			_, change := request.toServiceRepresentation()
+			require.Equal(t, tCase.expActor, change.Actor)
+
+			lines := CancelLinesRequest{OrderUUID: orderUUID, ProductUUIDs: []string{uuid.NewString()}, Actor: tCase.actor}
+			require.NoError(t, lines.validate())
+
+			_, _, change = lines.toServiceRepresentation()
+			require.Equal(t, tCase.expActor, change.Actor)
+		})
+	}
+
+	lines := CancelLinesRequest{OrderUUID: orderUUID, ProductUUIDs: []string{uuid.NewString()}, Actor: models.ActorPayment}
+	require.ErrorIs(t, lines.validate(), internalErrors.ErrInvalidActor)
+}
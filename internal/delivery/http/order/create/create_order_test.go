@@ -3,6 +3,9 @@ package create
 import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"strings"
 	"testing"
 )
 
@@ -17,8 +20,10 @@ func TestValidate(t *testing.T) {
 				UserUUID: uuid.New().String(),
 				Products: []Products{
 					{
-						UUID:   uuid.New().String(),
-						Amount: 320,
+						UUID:      uuid.New().String(),
+						Quantity:  2,
+						UnitPrice: 160,
+						Currency:  "RUB",
 					},
 				},
 				PaymentType: "card",
@@ -30,8 +35,10 @@ func TestValidate(t *testing.T) {
 				UserUUID: uuid.New().String(),
 				Products: []Products{
 					{
-						UUID:   uuid.New().String(),
-						Amount: 320,
+						UUID:      uuid.New().String(),
+						Quantity:  2,
+						UnitPrice: 160,
+						Currency:  "RUB",
 					},
 				},
 				PaymentType: "points",
@@ -42,13 +49,15 @@ func TestValidate(t *testing.T) {
 
 	for _, tCase := range tCases {
 		t.Run(tCase.name, func(t *testing.T) {
-			err := tCase.input.validate()
+			_, err := tCase.input.toDTO()
 			require.NoError(t, err)
 		})
 	}
 }
 
 func TestValidateError(t *testing.T) {
+	duplicateProduct := uuid.New().String()
+
 	tCases := []struct {
 		name   string
 		input  *CreateOrderRequest
@@ -57,7 +66,7 @@ func TestValidateError(t *testing.T) {
 		{
 			name:   "bad_user_uuid",
 			input:  &CreateOrderRequest{UserUUID: ""},
-			expErr: errInvalidUserUUID,
+			expErr: internalErrors.ErrInvalidUserUUID,
 		},
 		{
 			name: "bad_product_uuid",
@@ -70,18 +79,53 @@ func TestValidateError(t *testing.T) {
 					},
 				},
 			},
-			expErr: errInvalidProductUUID,
+			expErr: internalErrors.ErrInvalidProductUUID,
 		},
 		{
-			name: "bad_product_amount",
+			name: "bad_product_quantity",
 			input: &CreateOrderRequest{
 				UserUUID:    uuid.New().String(),
 				PaymentType: "card",
 				Products: []Products{
-					{UUID: uuid.New().String(), Amount: 0},
+					{UUID: uuid.New().String(), UnitPrice: 100, Currency: "RUB"},
 				},
 			},
-			expErr: errInvalidAmount,
+			expErr: internalErrors.ErrInvalidQuantity,
+		},
+		{
+			name: "bad_product_unit_price",
+			input: &CreateOrderRequest{
+				UserUUID:    uuid.New().String(),
+				PaymentType: "card",
+				Products: []Products{
+					{UUID: uuid.New().String(), Quantity: 1, Currency: "RUB"},
+				},
+			},
+			expErr: internalErrors.ErrInvalidUnitPrice,
+		},
+		{
+			name: "mixed_currency",
+			input: &CreateOrderRequest{
+				UserUUID:    uuid.New().String(),
+				PaymentType: "card",
+				Products: []Products{
+					{UUID: uuid.New().String(), Quantity: 1, UnitPrice: 100, Currency: "RUB"},
+					{UUID: uuid.New().String(), Quantity: 1, UnitPrice: 100, Currency: "EUR"},
+				},
+			},
+			expErr: internalErrors.ErrMixedCurrency,
+		},
+		{
+			name: "too_many_points",
+			input: &CreateOrderRequest{
+				UserUUID:    uuid.New().String(),
+				PaymentType: "points",
+				Products: []Products{
+					{UUID: uuid.New().String(), Quantity: 3, UnitPrice: 100, Currency: "RUB"},
+				},
+				WithPoints: 301,
+			},
+			expErr: internalErrors.ErrIncorrectPoints,
 		},
 		{
 			name: "no_products",
@@ -89,15 +133,38 @@ func TestValidateError(t *testing.T) {
 				UserUUID:    uuid.New().String(),
 				PaymentType: "card",
 			},
-			expErr: errEmptyProducts,
+			expErr: internalErrors.ErrEmptyProducts,
+		},
+		{
+			name: "duplicate_product",
+			input: &CreateOrderRequest{
+				UserUUID:    uuid.New().String(),
+				PaymentType: "card",
+				Products: []Products{
+					{UUID: duplicateProduct, Quantity: 1, UnitPrice: 100, Currency: "RUB"},
+					{UUID: duplicateProduct, Quantity: 2, UnitPrice: 100, Currency: "RUB"},
+				},
+			},
+			expErr: internalErrors.ErrDuplicateProduct,
+		},
+		{
+			name: "too_long_promo_code",
+			input: &CreateOrderRequest{
+				UserUUID:    uuid.New().String(),
+				PaymentType: "card",
+				Products: []Products{
+					{UUID: uuid.New().String(), Quantity: 1, UnitPrice: 100, Currency: "RUB"},
+				},
+				PromoCode: strings.Repeat("A", models.MaxPromoCodeLength+1),
+			},
+			expErr: internalErrors.ErrInvalidPromoCode,
 		},
 	}
 
 	for _, tCase := range tCases {
 		t.Run(tCase.name, func(t *testing.T) {
-			err := tCase.input.validate()
-			require.Error(t, err)
-			require.EqualError(t, tCase.expErr, err.Error())
+			_, err := tCase.input.toDTO()
+			require.ErrorIs(t, err, tCase.expErr)
 		})
 	}
 }
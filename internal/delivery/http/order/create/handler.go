@@ -3,9 +3,11 @@ package create
 import (
 	"context"
 	"encoding/json"
-	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 	"log/slog"
 	"net/http"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
 type orderCreator interface {
@@ -26,29 +28,27 @@ func NewHandler(log *slog.Logger, orderCreator orderCreator) *Handler {
 }
 
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.create_order.create"
 	var request CreateOrderRequest
 
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		h.log.Error("failed to decode request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.BadRequest(w, r, h.log, op, err)
 		return
 	}
 
-	if err = request.validate(); err != nil {
-		h.log.Error("failed to validate request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	order, err := request.toDTO()
+	if err != nil {
+		problem.InvalidRequest(w, r, h.log, op, err)
 		return
 	}
 
-	order := request.toDTO()
 	orderUUID, err := h.orderCreator.Create(
 		r.Context(),
 		&order,
 	)
 	if err != nil {
-		h.log.Error("failed to create order: ", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, h.log, op, err)
 		return
 	}
 
@@ -60,8 +60,6 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 			"order_uuid": orderUUID,
 		},
 	); err != nil {
-		h.log.Error("failed to encode response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
 	}
 }
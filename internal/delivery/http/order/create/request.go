@@ -1,34 +1,24 @@
 package create
 
 import (
-	"errors"
-	"fmt"
-
-	"github.com/google/uuid"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
-var (
-	errEmptyProducts = errors.New("products can't be empty")
-
-	errInvalidPaymentType = errors.New("invalid payment type")
-	errInvalidAmount      = errors.New("invalid price")
-	errInvalidProductUUID = errors.New("invalid product_uuid")
-	errInvalidUserUUID    = errors.New("invalid user_uuid")
-
-	errIncorrectPointsValue = errors.New("incorrect points value")
-)
-
 type CreateOrderRequest struct {
 	UserUUID    string     `json:"user_uuid"`
 	Products    []Products `json:"products"`
 	PaymentType string     `json:"payment_type"`
 	WithPoints  int        `json:"with_points"`
+	PromoCode   string     `json:"promo_code"`
 }
 
+// Products - строка заказа: цена за единицу в минорных единицах валюты
+// и код валюты ISO 4217
 type Products struct {
-	UUID   string `json:"uuid"`
-	Amount uint64 `json:"amount"`
+	UUID      string `json:"uuid"`
+	Quantity  uint32 `json:"quantity"`
+	UnitPrice uint64 `json:"unit_price"`
+	Currency  string `json:"currency"`
 }
 
 var paymentTypes = map[string]models.PaymentType{
@@ -36,57 +26,23 @@ var paymentTypes = map[string]models.PaymentType{
 	"points": models.Points,
 }
 
-func (req *CreateOrderRequest) validate() error {
-	_, err := uuid.Parse(req.UserUUID)
-	if err != nil {
-		return errInvalidUserUUID
-	}
-
-	pType, ok := paymentTypes[req.PaymentType]
-	if !ok {
-		return errInvalidPaymentType
-	}
-
-	if len(req.Products) == 0 {
-		return errEmptyProducts
-	}
-
-	var totalAmount uint64
+// toDTO проверяет запрос и собирает по нему заказ, проверки общие с gRPC API
+func (req *CreateOrderRequest) toDTO() (models.Order, error) {
+	lines := make([]models.CreateOrderLine, 0, len(req.Products))
 	for _, product := range req.Products {
-		if _, err = uuid.Parse(product.UUID); err != nil {
-			return fmt.Errorf("%w: %s", errInvalidProductUUID, err.Error())
-		}
-
-		if product.Amount == 0 {
-			return errInvalidAmount
-		}
-
-		totalAmount += product.Amount
-	}
-
-	if pType == models.Points {
-		if req.WithPoints < 0 || req.WithPoints > int(totalAmount) {
-			return errIncorrectPointsValue
-		}
-	}
-
-	return nil
-}
-
-func (req *CreateOrderRequest) toDTO() models.Order {
-	var products []models.Product
-	for _, product := range req.Products {
-		products = append(products, models.Product{
-			UUID:   uuid.MustParse(product.UUID),
-			Amount: product.Amount,
+		lines = append(lines, models.CreateOrderLine{
+			ProductUUID: product.UUID,
+			Quantity:    product.Quantity,
+			UnitPrice:   product.UnitPrice,
+			Currency:    product.Currency,
 		})
 	}
 
-	return models.Order{
-		Status:      models.OrderStatusCreated,
-		UserUUID:    uuid.MustParse(req.UserUUID),
+	return models.CreateOrderRequest{
+		UserUUID:    req.UserUUID,
 		PaymentType: paymentTypes[req.PaymentType],
-		Products:    products,
+		Products:    lines,
 		WithPoints:  req.WithPoints,
-	}
+		PromoCode:   req.PromoCode,
+	}.Order()
 }
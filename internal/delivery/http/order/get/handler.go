@@ -6,13 +6,17 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 )
 
 type orderGetter interface {
 	OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) ([]models.Order, error)
 	OrderByUUID(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+	StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error)
+	UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error)
 }
 
 type Handler struct {
@@ -28,28 +32,28 @@ func NewHandler(log *slog.Logger, orderGetter orderGetter) *Handler {
 	}
 }
 
+// OrdersByUUIDs отдаёт заказы по списку uuid. Список берётся из query-параметров
+// ?uuid=a&uuid=b, а если их нет - из JSON-тела запроса.
 func (h *Handler) OrdersByUUIDs(w http.ResponseWriter, r *http.Request) {
 	const op = "delivery.http.get_order.ordersByUUIDs"
 	var request OrdersByUUIDsRequest
 
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
-		h.log.Error(op, slog.String("failed to decode request", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if uuids, ok := r.URL.Query()[uuidQueryParam]; ok {
+		request.UUIDs = uuids
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
 		return
 	}
 
-	if err = request.validate(); err != nil {
-		h.log.Error(op, slog.String("failed to validate request", err.Error()))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := request.validate(); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
 		return
 	}
 
 	uuids := request.toServiceRepresentation()
 	orders, err := h.orderGetter.OrdersByUUIDs(r.Context(), uuids)
 	if err != nil {
-		h.log.Error(op, slog.String("failed to get orders", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, h.log, op, err)
 		return
 	}
 
@@ -62,7 +66,97 @@ func (h *Handler) OrdersByUUIDs(w http.ResponseWriter, r *http.Request) {
 		},
 	); err != nil {
 		h.log.Error(op, slog.String("failed to encode response", err.Error()))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) OrderByUUID(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.get_order.orderByUUID"
+
+	request := OrderByUUIDRequest{OrderUUID: chi.URLParam(r, "uuid")}
+
+	if err := request.validate(); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
+		return
+	}
+
+	order, err := h.orderGetter.OrderByUUID(r.Context(), request.toServiceRepresentation())
+	if err != nil {
+		problem.Error(w, r, h.log, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = json.NewEncoder(w).Encode(
+		map[string]interface{}{
+			"order": order,
+		},
+	); err != nil {
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
+}
+
+func (h *Handler) StatusHistory(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.get_order.statusHistory"
+
+	request := OrderByUUIDRequest{OrderUUID: chi.URLParam(r, "uuid")}
+
+	if err := request.validate(); err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
+		return
+	}
+
+	history, err := h.orderGetter.StatusHistory(r.Context(), request.toServiceRepresentation())
+	if err != nil {
+		problem.Error(w, r, h.log, op, err)
 		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = json.NewEncoder(w).Encode(
+		map[string]interface{}{
+			"history": history,
+		},
+	); err != nil {
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
+}
+
+func (h *Handler) UserOrders(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.get_order.userOrders"
+
+	request := newUserOrdersRequest(chi.URLParam(r, "user_uuid"), r.URL.Query())
+
+	filter, err := request.toServiceRepresentation()
+	if err != nil {
+		problem.BadRequest(w, r, h.log, op, err)
+		return
+	}
+
+	page, err := h.orderGetter.UserOrders(r.Context(), filter)
+	if err != nil {
+		problem.Error(w, r, h.log, op, err)
+		return
+	}
+
+	var nextCursor *string
+	if page.NextCursor != nil {
+		cursor := page.NextCursor.Encode()
+		nextCursor = &cursor
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = json.NewEncoder(w).Encode(
+		map[string]interface{}{
+			"orders":      page.Orders,
+			"next_cursor": nextCursor,
+		},
+	); err != nil {
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
 }
@@ -0,0 +1,271 @@
+package get
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+type fakeGetter struct {
+	orders  map[uuid.UUID]models.Order
+	history map[uuid.UUID][]models.StatusHistoryEntry
+
+	filter models.OrderFilter
+	page   *models.OrdersPage
+}
+
+func (g *fakeGetter) OrdersByUUIDs(_ context.Context, UUIDs []uuid.UUID) ([]models.Order, error) {
+	result := make([]models.Order, 0, len(UUIDs))
+	for _, id := range UUIDs {
+		if order, ok := g.orders[id]; ok {
+			result = append(result, order)
+		}
+	}
+
+	return result, nil
+}
+
+func (g *fakeGetter) OrderByUUID(_ context.Context, orderUUID uuid.UUID) (*models.Order, error) {
+	order, ok := g.orders[orderUUID]
+	if !ok {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return &order, nil
+}
+
+func (g *fakeGetter) StatusHistory(_ context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	if _, ok := g.orders[orderUUID]; !ok {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return g.history[orderUUID], nil
+}
+
+func (g *fakeGetter) UserOrders(_ context.Context, filter models.OrderFilter) (*models.OrdersPage, error) {
+	g.filter = filter
+
+	return g.page, nil
+}
+
+func newTestRouter(getter *fakeGetter) http.Handler {
+	h := NewHandler(slog.New(slog.NewTextHandler(io.Discard, nil)), getter)
+
+	mux := chi.NewRouter()
+	mux.Route("/order", func(r chi.Router) {
+		r.Get("/", h.OrdersByUUIDs)
+		r.Get("/{uuid}", h.OrderByUUID)
+		r.Get("/{uuid}/history", h.StatusHistory)
+	})
+	mux.Get("/users/{user_uuid}/orders", h.UserOrders)
+
+	return mux
+}
+
+func doGet(router http.Handler, target string, body io.Reader) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, body))
+
+	return w
+}
+
+func newFakeGetter(n int) (*fakeGetter, []uuid.UUID) {
+	getter := &fakeGetter{orders: make(map[uuid.UUID]models.Order, n)}
+	ids := make([]uuid.UUID, 0, n)
+	for i := 0; i < n; i++ {
+		id := uuid.New()
+		getter.orders[id] = models.Order{OrderUUID: id, UserUUID: uuid.New()}
+		ids = append(ids, id)
+	}
+
+	return getter, ids
+}
+
+func TestOrderByUUID(t *testing.T) {
+	getter, ids := newFakeGetter(1)
+	router := newTestRouter(getter)
+
+	w := doGet(router, "/order/"+ids[0].String(), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Order models.Order `json:"order"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, ids[0], resp.Order.OrderUUID)
+
+	w = doGet(router, "/order/"+uuid.NewString(), nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, problem.CodeOrderNotFound, p.Code)
+
+	require.Equal(t, http.StatusBadRequest, doGet(router, "/order/not-a-uuid", nil).Code)
+}
+
+func TestStatusHistory(t *testing.T) {
+	getter, ids := newFakeGetter(1)
+	changedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	getter.history = map[uuid.UUID][]models.StatusHistoryEntry{
+		ids[0]: {
+			{OrderUUID: ids[0], ToStatus: models.OrderStatusCreated, Actor: models.ActorCustomer, Reason: "order created", ChangedAt: changedAt},
+			{
+				OrderUUID: ids[0], FromStatus: models.OrderStatusCreated, ToStatus: models.OrderStatusPaid,
+				Actor: models.ActorPayment, Reason: "payment succeeded", ChangedAt: changedAt.Add(time.Minute),
+			},
+		},
+	}
+	router := newTestRouter(getter)
+
+	w := doGet(router, "/order/"+ids[0].String()+"/history", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		History []models.StatusHistoryEntry `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, getter.history[ids[0]], resp.History)
+
+	w = doGet(router, "/order/"+uuid.NewString()+"/history", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, problem.CodeOrderNotFound, p.Code)
+
+	require.Equal(t, http.StatusBadRequest, doGet(router, "/order/not-a-uuid/history", nil).Code)
+}
+
+func TestOrdersByUUIDs(t *testing.T) {
+	getter, ids := newFakeGetter(3)
+	router := newTestRouter(getter)
+
+	tCases := []struct {
+		name   string
+		target string
+		body   io.Reader
+	}{
+		{
+			name:   "query",
+			target: "/order?uuid=" + ids[0].String() + "&uuid=" + ids[1].String(),
+		},
+		{
+			name:   "query_trailing_slash",
+			target: "/order/?uuid=" + ids[0].String() + "&uuid=" + ids[1].String(),
+		},
+		{
+			name:   "body",
+			target: "/order/",
+			body:   strings.NewReader(`{"uuids": ["` + ids[0].String() + `", "` + ids[1].String() + `"]}`),
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			w := doGet(router, tCase.target, tCase.body)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Orders []models.Order `json:"orders"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+			got := make([]uuid.UUID, 0, len(resp.Orders))
+			for _, order := range resp.Orders {
+				got = append(got, order.OrderUUID)
+			}
+			require.ElementsMatch(t, ids[:2], got)
+		})
+	}
+}
+
+func TestOrdersByUUIDsInvalidQuery(t *testing.T) {
+	router := newTestRouter(&fakeGetter{})
+
+	require.Equal(t, http.StatusBadRequest, doGet(router, "/order?uuid=bad", nil).Code)
+	require.Equal(t, http.StatusBadRequest, doGet(router, "/order?uuid=", nil).Code)
+}
+
+func TestUserOrders(t *testing.T) {
+	userUUID := uuid.New()
+	next := &models.OrderCursor{CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 123000, time.UTC), OrderUUID: uuid.New()}
+
+	getter := &fakeGetter{page: &models.OrdersPage{
+		Orders:     []models.Order{{OrderUUID: next.OrderUUID, UserUUID: userUUID}},
+		NextCursor: next,
+	}}
+	router := newTestRouter(getter)
+
+	target := "/users/" + userUUID.String() + "/orders?status=created&status=paid&payment_type=card" +
+		"&created_from=2024-05-01T00:00:00%2B03:00&created_to=2024-06-01T00:00:00Z&limit=1"
+
+	w := doGet(router, target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, models.OrderFilter{
+		UserUUID:    userUUID,
+		Statuses:    []models.OrderStatus{models.OrderStatusCreated, models.OrderStatusPaid},
+		PaymentType: models.Card,
+		CreatedFrom: time.Date(2024, 4, 30, 21, 0, 0, 0, time.UTC),
+		CreatedTo:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Limit:       1,
+	}, getter.filter)
+
+	var resp struct {
+		Orders     []models.Order `json:"orders"`
+		NextCursor *string        `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Orders, 1)
+	require.NotNil(t, resp.NextCursor)
+
+	// следующая страница запрашивается с курсором из ответа
+	getter.page = &models.OrdersPage{}
+	w = doGet(router, "/users/"+userUUID.String()+"/orders?cursor="+*resp.NextCursor, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, next, getter.filter.After)
+	require.Equal(t, defaultUserOrdersLimit, getter.filter.Limit)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Nil(t, resp.NextCursor)
+}
+
+func TestUserOrdersValidateError(t *testing.T) {
+	userOrders := "/users/" + uuid.NewString() + "/orders"
+
+	tCases := []struct {
+		name   string
+		target string
+	}{
+		{name: "bad_user_uuid", target: "/users/bad/orders"},
+		{name: "bad_status", target: userOrders + "?status=lost"},
+		{name: "bad_payment_type", target: userOrders + "?payment_type=cash"},
+		{name: "bad_created_from", target: userOrders + "?created_from=yesterday"},
+		{name: "empty_created_range", target: userOrders + "?created_from=2024-06-01T00:00:00Z&created_to=2024-05-01T00:00:00Z"},
+		{name: "bad_cursor", target: userOrders + "?cursor=bad"},
+		{name: "zero_limit", target: userOrders + "?limit=0"},
+		{name: "big_limit", target: userOrders + "?limit=1000"},
+	}
+
+	router := newTestRouter(&fakeGetter{page: &models.OrdersPage{}})
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			require.Equal(t, http.StatusBadRequest, doGet(router, tCase.target, nil).Code)
+		})
+	}
+}
@@ -2,14 +2,48 @@ package get
 
 import (
 	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// uuidQueryParam - query-параметр пакетного чтения: GET /order?uuid=a&uuid=b
+const uuidQueryParam = "uuid"
+
+const (
+	defaultUserOrdersLimit = 20
+	maxUserOrdersLimit     = 100
 )
 
 var (
 	errEmptyOrderIDs    = errors.New("no order ids passed")
 	errInvalidOrderUUID = errors.New("invalid order_uuid")
+
+	errInvalidUserUUID    = errors.New("invalid user_uuid")
+	errInvalidStatus      = errors.New("invalid status")
+	errInvalidPaymentType = errors.New("invalid payment_type")
+	errInvalidCreatedFrom = errors.New("invalid created_from, expected RFC 3339 time")
+	errInvalidCreatedTo   = errors.New("invalid created_to, expected RFC 3339 time")
+	errInvalidCreatedAt   = errors.New("created_from should be before created_to")
+	errInvalidCursor      = errors.New("invalid cursor")
+	errInvalidLimit       = errors.New("invalid limit")
 )
 
+var orderStatuses = map[string]models.OrderStatus{
+	"created":   models.OrderStatusCreated,
+	"paid":      models.OrderStatusPaid,
+	"delivered": models.OrderStatusDelivered,
+	"canceled":  models.OrderStatusCanceled,
+}
+
+var paymentTypes = map[string]models.PaymentType{
+	"card":   models.Card,
+	"points": models.Points,
+}
+
 type OrdersByUUIDsRequest struct {
 	UUIDs []string `json:"uuids"`
 }
@@ -53,3 +87,88 @@ func (r *OrderByUUIDRequest) validate() error {
 func (r *OrderByUUIDRequest) toServiceRepresentation() uuid.UUID {
 	return uuid.MustParse(r.OrderUUID)
 }
+
+// UserOrdersRequest - GET /users/{user_uuid}/orders. Фильтры передаются
+// query-параметрами: status (можно несколько раз), payment_type,
+// created_from и created_to в RFC 3339, cursor и limit.
+type UserOrdersRequest struct {
+	UserUUID    string
+	Statuses    []string
+	PaymentType string
+	CreatedFrom string
+	CreatedTo   string
+	Cursor      string
+	Limit       string
+}
+
+func newUserOrdersRequest(userUUID string, query url.Values) UserOrdersRequest {
+	return UserOrdersRequest{
+		UserUUID:    userUUID,
+		Statuses:    query["status"],
+		PaymentType: query.Get("payment_type"),
+		CreatedFrom: query.Get("created_from"),
+		CreatedTo:   query.Get("created_to"),
+		Cursor:      query.Get("cursor"),
+		Limit:       query.Get("limit"),
+	}
+}
+
+// toServiceRepresentation проверяет запрос и собирает из него фильтр.
+// Время приводится к UTC: created_at хранится как timestamp без часового пояса.
+func (r *UserOrdersRequest) toServiceRepresentation() (models.OrderFilter, error) {
+	filter := models.OrderFilter{Limit: defaultUserOrdersLimit}
+
+	userUUID, err := uuid.Parse(r.UserUUID)
+	if err != nil {
+		return filter, errInvalidUserUUID
+	}
+	filter.UserUUID = userUUID
+
+	for _, name := range r.Statuses {
+		status, ok := orderStatuses[name]
+		if !ok {
+			return filter, errInvalidStatus
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	if r.PaymentType != "" {
+		paymentType, ok := paymentTypes[r.PaymentType]
+		if !ok {
+			return filter, errInvalidPaymentType
+		}
+		filter.PaymentType = paymentType
+	}
+
+	if r.CreatedFrom != "" {
+		if filter.CreatedFrom, err = time.Parse(time.RFC3339Nano, r.CreatedFrom); err != nil {
+			return filter, errInvalidCreatedFrom
+		}
+		filter.CreatedFrom = filter.CreatedFrom.UTC()
+	}
+
+	if r.CreatedTo != "" {
+		if filter.CreatedTo, err = time.Parse(time.RFC3339Nano, r.CreatedTo); err != nil {
+			return filter, errInvalidCreatedTo
+		}
+		filter.CreatedTo = filter.CreatedTo.UTC()
+	}
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return filter, errInvalidCreatedAt
+	}
+
+	if r.Cursor != "" {
+		if filter.After, err = models.ParseOrderCursor(r.Cursor); err != nil {
+			return filter, errInvalidCursor
+		}
+	}
+
+	if r.Limit != "" {
+		if filter.Limit, err = strconv.Atoi(r.Limit); err != nil || filter.Limit <= 0 || filter.Limit > maxUserOrdersLimit {
+			return filter, errInvalidLimit
+		}
+	}
+
+	return filter, nil
+}
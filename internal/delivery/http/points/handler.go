@@ -0,0 +1,56 @@
+package points
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/delivery/http/problem"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+var errInvalidUserUUID = errors.New("invalid user_uuid")
+
+type balanceGetter interface {
+	Balance(ctx context.Context, userUUID uuid.UUID) (*models.PointsBalance, error)
+}
+
+type Handler struct {
+	log *slog.Logger
+
+	balanceGetter balanceGetter
+}
+
+func NewHandler(log *slog.Logger, balanceGetter balanceGetter) *Handler {
+	return &Handler{
+		log:           log,
+		balanceGetter: balanceGetter,
+	}
+}
+
+func (h *Handler) Balance(w http.ResponseWriter, r *http.Request) {
+	const op = "delivery.http.points.balance"
+
+	userUUID, err := uuid.Parse(chi.URLParam(r, "user_uuid"))
+	if err != nil {
+		problem.BadRequest(w, r, h.log, op, errInvalidUserUUID)
+		return
+	}
+
+	balance, err := h.balanceGetter.Balance(r.Context(), userUUID)
+	if err != nil {
+		problem.Error(w, r, h.log, op, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err = json.NewEncoder(w).Encode(balance); err != nil {
+		h.log.Error(op, slog.String("failed to encode response", err.Error()))
+	}
+}
@@ -0,0 +1,177 @@
+// Package problem переводит ошибки в ответы application/problem+json (RFC 7807).
+//
+// Клиент получает статус и стабильный код ошибки из таблицы доменных ошибок,
+// а текст исходной ошибки с именами операций попадает только в лог.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+const ContentType = "application/problem+json"
+
+// typePrefix - префикс URI типа проблемы, к нему добавляется код ошибки
+const typePrefix = "urn:order-service:problem:"
+
+// Стабильные коды ошибок. Клиенты могут на них опираться, поэтому коды
+// не переименовываются.
+const (
+	CodeInvalidRequest           = "invalid_request"
+	CodeNotFound                 = "not_found"
+	CodeMethodNotAllowed         = "method_not_allowed"
+	CodeOrderNotFound            = "order_not_found"
+	CodeOrderLineNotFound        = "order_line_not_found"
+	CodeOrderAlreadyCanceled     = "order_already_canceled"
+	CodeOrderAlreadyDelivered    = "order_already_delivered"
+	CodeOrderAlreadyPaid         = "order_already_paid"
+	CodeOrderNotPaid             = "order_not_paid"
+	CodeOrderCannotBeCanceled    = "order_cannot_be_canceled"
+	CodeInvalidStatusTransition  = "invalid_status_transition"
+	CodeInvalidCurrency          = "invalid_currency"
+	CodeMixedCurrency            = "mixed_currency"
+	CodeAmountOverflow           = "amount_overflow"
+	CodeIncorrectPoints          = "incorrect_points"
+	CodeInsufficientPoints       = "insufficient_points"
+	CodePromoCodeNotFound        = "promo_code_not_found"
+	CodePromoCodeInactive        = "promo_code_inactive"
+	CodePromoCodeNotApplicable   = "promo_code_not_applicable"
+	CodePromoCodeLimitReached    = "promo_code_limit_reached"
+	CodeIdempotencyKeyReused     = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress = "idempotency_key_in_progress"
+	CodeInternal                 = "internal_error"
+)
+
+// Problem - тело ответа по RFC 7807, расширенное стабильным кодом ошибки
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+type mapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// domainErrors проверяются по порядку: ошибка перехода статуса оборачивает
+// и общую ErrInvalidStatusTransition, и более точную причину, поэтому точные
+// причины стоят выше.
+var domainErrors = []mapping{
+	{internalErrors.ErrOrderNotFound, http.StatusNotFound, CodeOrderNotFound},
+	{internalErrors.ErrOrderLineNotFound, http.StatusUnprocessableEntity, CodeOrderLineNotFound},
+	{internalErrors.ErrOrderAlreadyCanceled, http.StatusConflict, CodeOrderAlreadyCanceled},
+	{internalErrors.ErrOrderAlreadyDelivered, http.StatusConflict, CodeOrderAlreadyDelivered},
+	{internalErrors.ErrOrderAlreadyPaid, http.StatusConflict, CodeOrderAlreadyPaid},
+	{internalErrors.ErrOrderNotPaid, http.StatusConflict, CodeOrderNotPaid},
+	{internalErrors.ErrCancelOrderByStatus, http.StatusConflict, CodeOrderCannotBeCanceled},
+	{internalErrors.ErrInvalidStatusTransition, http.StatusConflict, CodeInvalidStatusTransition},
+	{internalErrors.ErrInvalidCurrency, http.StatusUnprocessableEntity, CodeInvalidCurrency},
+	{internalErrors.ErrMixedCurrency, http.StatusUnprocessableEntity, CodeMixedCurrency},
+	{internalErrors.ErrAmountOverflow, http.StatusUnprocessableEntity, CodeAmountOverflow},
+	{internalErrors.ErrIncorrectPoints, http.StatusUnprocessableEntity, CodeIncorrectPoints},
+	{internalErrors.ErrInsufficientPoints, http.StatusUnprocessableEntity, CodeInsufficientPoints},
+	{internalErrors.ErrPromoCodeNotFound, http.StatusUnprocessableEntity, CodePromoCodeNotFound},
+	{internalErrors.ErrPromoCodeInactive, http.StatusUnprocessableEntity, CodePromoCodeInactive},
+	{internalErrors.ErrPromoCodeNotApplicable, http.StatusUnprocessableEntity, CodePromoCodeNotApplicable},
+	{internalErrors.ErrPromoCodeLimitReached, http.StatusUnprocessableEntity, CodePromoCodeLimitReached},
+	{internalErrors.ErrIdempotencyKeyInProgress, http.StatusConflict, CodeIdempotencyKeyInProgress},
+	{internalErrors.ErrIdempotencyKeyReused, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused},
+}
+
+// FromError строит ответ на ошибку сервиса. Для доменной ошибки в detail
+// попадает её собственный текст без обёрток, для неизвестной - ничего.
+func FromError(err error) Problem {
+	if m, ok := domainMapping(err); ok {
+		return New(m.status, m.code, m.err.Error())
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "")
+}
+
+func domainMapping(err error) (mapping, bool) {
+	for _, m := range domainErrors {
+		if errors.Is(err, m.err) {
+			return m, true
+		}
+	}
+
+	return mapping{}, false
+}
+
+func New(status int, code, detail string) Problem {
+	return Problem{
+		Type:   typePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Error пишет ответ на ошибку сервиса и логирует её исходный текст.
+// Ошибки клиента логируются как предупреждения, 5xx - как ошибки.
+func Error(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, err error) {
+	p := FromError(err)
+
+	level := slog.LevelWarn
+	if p.Status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	log.Log(r.Context(), level, op,
+		slog.String("code", p.Code),
+		slog.Int("status", p.Status),
+		slog.String("error", err.Error()),
+	)
+
+	Write(w, r, p)
+}
+
+// BadRequest пишет ответ на невалидный запрос. Текст ошибки валидации
+// формируется в слое доставки и отдаётся клиенту как есть.
+func BadRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, err error) {
+	log.WarnContext(r.Context(), op, slog.String("invalid request", err.Error()))
+
+	Write(w, r, New(http.StatusBadRequest, CodeInvalidRequest, err.Error()))
+}
+
+// InvalidRequest пишет ответ на ошибку проверки запроса. Доменная ошибка,
+// например из подсчёта сумм, получает свой статус и код, как в Error,
+// остальные - 400, как в BadRequest.
+func InvalidRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, err error) {
+	if _, ok := domainMapping(err); ok {
+		Error(w, r, log, op, err)
+		return
+	}
+
+	BadRequest(w, r, log, op, err)
+}
+
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// NotFound и MethodNotAllowed заменяют текстовые ответы роутера по умолчанию
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusNotFound, CodeNotFound, ""))
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, ""))
+}
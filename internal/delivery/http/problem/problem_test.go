@@ -0,0 +1,110 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestFromError(t *testing.T) {
+	tCases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{
+			name:   "not_found",
+			err:    fmt.Errorf("service.order.Cancel: %w", internalErrors.ErrOrderNotFound),
+			status: http.StatusNotFound,
+			code:   CodeOrderNotFound,
+		},
+		{
+			name:   "already_canceled",
+			err:    fmt.Errorf("service.order.Cancel: %w", &models.StatusTransitionError{From: models.OrderStatusCanceled, To: models.OrderStatusCanceled}),
+			status: http.StatusConflict,
+			code:   CodeOrderAlreadyCanceled,
+		},
+		{
+			name:   "invalid_transition",
+			err:    &models.StatusTransitionError{From: models.OrderStatusCreated, To: models.OrderStatusDelivered},
+			status: http.StatusConflict,
+			code:   CodeOrderNotPaid,
+		},
+		{
+			name:   "idempotency_key_reused",
+			err:    fmt.Errorf("repository.idempotency.Reserve: %w", internalErrors.ErrIdempotencyKeyReused),
+			status: http.StatusUnprocessableEntity,
+			code:   CodeIdempotencyKeyReused,
+		},
+		{
+			name:   "mixed_currency",
+			err:    fmt.Errorf("services.order.createOrder: %w", internalErrors.ErrMixedCurrency),
+			status: http.StatusUnprocessableEntity,
+			code:   CodeMixedCurrency,
+		},
+		{
+			name:   "unknown",
+			err:    errors.New("repository.order.Create: pq: connection refused"),
+			status: http.StatusInternalServerError,
+			code:   CodeInternal,
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			p := FromError(tCase.err)
+			require.Equal(t, tCase.status, p.Status)
+			require.Equal(t, tCase.code, p.Code)
+			require.Equal(t, typePrefix+tCase.code, p.Type)
+			require.NotContains(t, p.Detail, "service.")
+			require.NotContains(t, p.Detail, "repository.")
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	var logs strings.Builder
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/order/cancel", nil)
+	Error(w, r, log, "delivery.http.cancel_order.cancel",
+		fmt.Errorf("service.order.Cancel: %w", internalErrors.ErrOrderNotFound))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	require.Equal(t, Problem{
+		Type:     typePrefix + CodeOrderNotFound,
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   internalErrors.ErrOrderNotFound.Error(),
+		Instance: "/order/cancel",
+		Code:     CodeOrderNotFound,
+	}, p)
+
+	// исходная ошибка остаётся только в логе
+	require.Contains(t, logs.String(), "service.order.Cancel")
+}
+
+func TestErrorHidesInternalCause(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/order/", nil)
+	Error(w, r, slog.New(slog.NewTextHandler(io.Discard, nil)), "op", errors.New("pq: password authentication failed"))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NotContains(t, w.Body.String(), "pq:")
+}
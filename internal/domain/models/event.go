@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventType string
+
+const (
+	EventTypeOrderCreated   EventType = "OrderCreated"
+	EventTypeOrderPaid      EventType = "OrderPaid"
+	EventTypeOrderDelivered EventType = "OrderDelivered"
+	EventTypeOrderCanceled  EventType = "OrderCanceled"
+
+	// EventTypeOrderLinesCanceled - отмена части строк заказа, payload - LinesCanceledEvent
+	EventTypeOrderLinesCanceled EventType = "OrderLinesCanceled"
+)
+
+var eventTypesByStatus = map[OrderStatus]EventType{
+	OrderStatusCreated:   EventTypeOrderCreated,
+	OrderStatusPaid:      EventTypeOrderPaid,
+	OrderStatusDelivered: EventTypeOrderDelivered,
+	OrderStatusCanceled:  EventTypeOrderCanceled,
+}
+
+// EventTypeByStatus возвращает тип события, которое публикуется при переходе заказа в статус status
+func EventTypeByStatus(status OrderStatus) EventType {
+	return eventTypesByStatus[status]
+}
+
+// OutboxEvent - запись таблицы outbox. Payload содержит полный снимок заказа
+// на момент события, чтобы потребителям не нужно было ходить в сервис заказов.
+type OutboxEvent struct {
+	ID               int64           `json:"-"`
+	Attempts         int             `json:"-"`
+	EventUUID        uuid.UUID       `json:"event_uuid"`
+	EventType        EventType       `json:"event_type"`
+	OrderUUID        uuid.UUID       `json:"order_uuid"`
+	AggregateVersion int             `json:"aggregate_version"`
+	CreatedAt        time.Time       `json:"created_at"`
+	Payload          json.RawMessage `json:"payload"`
+}
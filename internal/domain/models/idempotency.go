@@ -0,0 +1,7 @@
+package models
+
+// IdempotentResponse - сохранённый ответ на запрос с ключом идемпотентности
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
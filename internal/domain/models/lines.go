@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// LinesCancellation - итог отмены строк заказа. RefundAmount - на сколько
+// уменьшилась сумма к оплате, у неоплаченного заказа возвращать нечего и она
+// равна нулю. PointsRefund - сколько баллов вернулось пользователю. Status - статус заказа после отмены: с последней строкой
+// заказ отменяется целиком.
+type LinesCancellation struct {
+	OrderUUID    uuid.UUID   `json:"order_uuid"`
+	Products     []Product   `json:"products"`
+	RefundAmount uint64      `json:"refund_amount"`
+	PointsRefund uint64      `json:"points_refund"`
+	Status       OrderStatus `json:"status"`
+}
+
+// LinesCanceledEvent - payload события OrderLinesCanceled: снимок заказа
+// после отмены и отменённые строки
+type LinesCanceledEvent struct {
+	Order
+	LinesCanceled LinesCancellation `json:"lines_canceled"`
+}
+
+// CancelLines отменяет строки заказа с товарами productUUIDs и пересчитывает
+// суммы по оставшимся строкам. promo - промокод заказа или nil: скидка по нему
+// пересчитывается и может пропасть, если оставшиеся строки под него не
+// подходят. Баллов списывается не больше новой суммы, лишние возвращаются.
+// Отменить строки можно только у заказа, который можно отменить целиком.
+func (oe *Order) CancelLines(productUUIDs []uuid.UUID, promo *PromoCode) (LinesCancellation, error) {
+	if err := oe.Status.ValidateTransition(OrderStatusCanceled); err != nil {
+		return LinesCancellation{}, err
+	}
+
+	toCancel := make(map[uuid.UUID]bool, len(productUUIDs))
+	for _, productUUID := range productUUIDs {
+		toCancel[productUUID] = false
+	}
+
+	var kept, canceled []Product
+	for _, product := range oe.Products {
+		if _, ok := toCancel[product.UUID]; ok {
+			toCancel[product.UUID] = true
+			canceled = append(canceled, product)
+			continue
+		}
+		kept = append(kept, product)
+	}
+
+	for _, productUUID := range productUUIDs {
+		if !toCancel[productUUID] {
+			return LinesCancellation{}, fmt.Errorf("%w: %s", internal_errors.ErrOrderLineNotFound, productUUID)
+		}
+	}
+	if len(canceled) == 0 {
+		return LinesCancellation{}, internal_errors.ErrOrderLineNotFound
+	}
+
+	updated := *oe
+	updated.Products = kept
+
+	status := oe.Status
+	if len(kept) == 0 {
+		updated.TotalAmount, updated.PromoDiscount, updated.PointsDiscount, updated.GrandTotal = 0, 0, 0, 0
+		updated.WithPoints = 0
+		status = OrderStatusCanceled
+	} else if err := updated.recalculateLines(promo); err != nil {
+		return LinesCancellation{}, err
+	}
+
+	// скидки считаются от суммы строк и не растут быстрее неё, поэтому
+	// суммы после отмены строк не больше прежних
+	cancellation := LinesCancellation{
+		OrderUUID:    oe.OrderUUID,
+		Products:     canceled,
+		PointsRefund: oe.PointsDiscount - updated.PointsDiscount,
+		Status:       status,
+	}
+	if oe.Status == OrderStatusPaid {
+		cancellation.RefundAmount = oe.GrandTotal - updated.GrandTotal
+	}
+	*oe = updated
+
+	return cancellation, nil
+}
+
+// recalculateLines пересчитывает суммы заказа после изменения строк
+func (oe *Order) recalculateLines(promo *PromoCode) error {
+	withPoints := oe.WithPoints
+
+	oe.PromoDiscount, oe.WithPoints = 0, 0
+	if err := oe.CalculateTotals(); err != nil {
+		return err
+	}
+
+	if promo != nil {
+		discount, err := promo.discount(oe)
+		if err != nil && !errors.Is(err, internal_errors.ErrPromoCodeNotApplicable) {
+			return err
+		}
+		oe.PromoDiscount = discount
+	}
+
+	oe.WithPoints = withPoints
+	if payable := oe.TotalAmount - oe.PromoDiscount; oe.PaymentType == Points && uint64(withPoints) > payable {
+		oe.WithPoints = int(payable)
+	}
+
+	return oe.CalculateTotals()
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestCancelLines(t *testing.T) {
+	now := time.Now()
+	promo := PromoCode{
+		Code: "TEN", Kind: PromoPercentage, Percent: 10,
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour),
+	}
+
+	order := &Order{
+		OrderUUID:   uuid.New(),
+		Status:      OrderStatusPaid,
+		PaymentType: Points,
+		WithPoints:  5000,
+		Products: []Product{
+			{UUID: uuid.New(), Quantity: 5, UnitPrice: 1000, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: 3000, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 2, UnitPrice: 1000, Currency: "RUB"},
+		},
+	}
+	require.NoError(t, order.ApplyPromo(promo, now))
+	require.Equal(t, uint64(1000), order.PromoDiscount)
+	require.Equal(t, uint64(4000), order.GrandTotal)
+
+	// скидка по промокоду пересчитывается от новой суммы, баллы остаются
+	cancellation, err := order.CancelLines([]uuid.UUID{order.Products[1].UUID}, &promo)
+	require.NoError(t, err)
+	require.Len(t, cancellation.Products, 1)
+	require.Equal(t, OrderStatusPaid, cancellation.Status)
+	require.Equal(t, uint64(7000), order.TotalAmount)
+	require.Equal(t, uint64(700), order.PromoDiscount)
+	require.Equal(t, uint64(5000), order.PointsDiscount)
+	require.Equal(t, uint64(1300), order.GrandTotal)
+	require.Equal(t, uint64(2700), cancellation.RefundAmount)
+	require.Zero(t, cancellation.PointsRefund)
+
+	// баллов больше новой суммы - лишние возвращаются
+	cancellation, err = order.CancelLines([]uuid.UUID{order.Products[0].UUID}, &promo)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2000), order.TotalAmount)
+	require.Equal(t, uint64(200), order.PromoDiscount)
+	require.Equal(t, uint64(1800), order.PointsDiscount)
+	require.Zero(t, order.GrandTotal)
+	require.Equal(t, uint64(1300), cancellation.RefundAmount)
+	require.Equal(t, uint64(3200), cancellation.PointsRefund)
+
+	// последняя строка отменяет заказ
+	cancellation, err = order.CancelLines([]uuid.UUID{order.Products[0].UUID}, &promo)
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusCanceled, cancellation.Status)
+	require.Empty(t, order.Products)
+	require.Zero(t, order.TotalAmount)
+	require.Zero(t, order.GrandTotal)
+	require.Equal(t, uint64(1800), cancellation.PointsRefund)
+}
+
+func TestCancelLinesUnpaid(t *testing.T) {
+	order := &Order{
+		Status:      OrderStatusCreated,
+		PaymentType: Card,
+		Products: []Product{
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: 1000, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: 3000, Currency: "RUB"},
+		},
+	}
+	require.NoError(t, order.CalculateTotals())
+
+	// неоплаченный заказ возвращать нечего, меняется только сумма к оплате
+	cancellation, err := order.CancelLines([]uuid.UUID{order.Products[1].UUID}, nil)
+	require.NoError(t, err)
+	require.Zero(t, cancellation.RefundAmount)
+	require.Equal(t, uint64(1000), order.GrandTotal)
+}
+
+func TestCancelLinesError(t *testing.T) {
+	newOrder := func(status OrderStatus) *Order {
+		order := &Order{
+			Status: status,
+			Products: []Product{
+				{UUID: uuid.New(), Quantity: 1, UnitPrice: 1000, Currency: "RUB"},
+			},
+		}
+		require.NoError(t, order.CalculateTotals())
+		return order
+	}
+
+	order := newOrder(OrderStatusCreated)
+	_, err := order.CancelLines([]uuid.UUID{order.Products[0].UUID, uuid.New()}, nil)
+	require.ErrorIs(t, err, internal_errors.ErrOrderLineNotFound)
+	require.Len(t, order.Products, 1)
+
+	_, err = order.CancelLines(nil, nil)
+	require.ErrorIs(t, err, internal_errors.ErrOrderLineNotFound)
+
+	order = newOrder(OrderStatusDelivered)
+	_, err = order.CancelLines([]uuid.UUID{order.Products[0].UUID}, nil)
+	require.ErrorIs(t, err, internal_errors.ErrInvalidStatusTransition)
+	require.Equal(t, uint64(1000), order.TotalAmount)
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/google/uuid"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"golang.org/x/text/currency"
+)
+
+// MaxAmount - наибольшая сумма в минорных единицах. Суммы хранятся в bigint,
+// поэтому предел - math.MaxInt64, а не math.MaxUint64.
+const MaxAmount = math.MaxInt64
+
+// ValidateCurrency проверяет, что code - код валюты ISO 4217 в верхнем регистре
+func ValidateCurrency(code string) error {
+	unit, err := currency.ParseISO(code)
+	if err != nil || unit.String() != code {
+		return fmt.Errorf("%w: %q", internal_errors.ErrInvalidCurrency, code)
+	}
+
+	return nil
+}
+
+// MulAmount умножает сумму на количество без переполнения
+func MulAmount(amount uint64, quantity uint64) (uint64, error) {
+	hi, lo := bits.Mul64(amount, quantity)
+	if hi != 0 || lo > MaxAmount {
+		return 0, internal_errors.ErrAmountOverflow
+	}
+
+	return lo, nil
+}
+
+// AddAmounts складывает суммы без переполнения
+func AddAmounts(a, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 || sum > MaxAmount {
+		return 0, internal_errors.ErrAmountOverflow
+	}
+
+	return sum, nil
+}
+
+// Total - стоимость строки заказа: цена за единицу, умноженная на количество
+func (p Product) Total() (uint64, error) {
+	return MulAmount(p.UnitPrice, uint64(p.Quantity))
+}
+
+// CalculateTotals считает суммы заказа по строкам: TotalAmount, PointsDiscount
+// и GrandTotal, и заполняет Currency. Все строки заказа должны быть в одной
+// валюте. PromoDiscount не пересчитывается, его выставляет ApplyPromo. Баллы
+// списываются только при оплате баллами, один балл - одна минорная единица
+// валюты, и не могут превышать сумму строк за вычетом скидки по промокоду.
+func (oe *Order) CalculateTotals() error {
+	if len(oe.Products) == 0 {
+		return nil
+	}
+
+	orderCurrency := oe.Products[0].Currency
+	if err := ValidateCurrency(orderCurrency); err != nil {
+		return err
+	}
+
+	var total uint64
+	for _, product := range oe.Products {
+		if product.Currency != orderCurrency {
+			return fmt.Errorf("%w: %s and %s", internal_errors.ErrMixedCurrency, orderCurrency, product.Currency)
+		}
+
+		lineTotal, err := product.Total()
+		if err != nil {
+			return fmt.Errorf("product %s: %w", product.UUID, err)
+		}
+
+		if total, err = AddAmounts(total, lineTotal); err != nil {
+			return err
+		}
+	}
+
+	if oe.PromoDiscount > total {
+		return fmt.Errorf("%w: promo discount exceeds order total", internal_errors.ErrPromoCodeNotApplicable)
+	}
+	payable := total - oe.PromoDiscount
+
+	var discount uint64
+	if oe.PaymentType == Points {
+		if oe.WithPoints < 0 || uint64(oe.WithPoints) > payable {
+			return internal_errors.ErrIncorrectPoints
+		}
+		discount = uint64(oe.WithPoints)
+	}
+
+	oe.Currency = orderCurrency
+	oe.TotalAmount = total
+	oe.PointsDiscount = discount
+	oe.GrandTotal = payable - discount
+
+	return nil
+}
+
+// TotalsMismatch - заказ, хранимые суммы которого не сходятся со строками.
+// LinesTotal - сумма строк в десятичной записи: у испорченных строк она может
+// не поместиться в uint64. PromoDiscountLine - сумма строки скидки по
+// промокоду, ноль без неё.
+type TotalsMismatch struct {
+	OrderUUID         uuid.UUID
+	Currency          string
+	TotalAmount       uint64
+	PromoDiscount     uint64
+	PromoDiscountLine uint64
+	PointsDiscount    uint64
+	GrandTotal        uint64
+	LinesTotal        string
+	LinesCurrency     string
+}
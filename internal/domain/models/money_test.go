@@ -0,0 +1,130 @@
+package models
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestValidateCurrency(t *testing.T) {
+	require.NoError(t, ValidateCurrency("RUB"))
+	require.NoError(t, ValidateCurrency("USD"))
+
+	for _, code := range []string{"", "rub", "RU", "RUBL", "ABC"} {
+		require.ErrorIs(t, ValidateCurrency(code), internal_errors.ErrInvalidCurrency, code)
+	}
+}
+
+func TestMulAmount(t *testing.T) {
+	total, err := MulAmount(250, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), total)
+
+	total, err = MulAmount(MaxAmount, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(MaxAmount), total)
+
+	_, err = MulAmount(MaxAmount, 2)
+	require.ErrorIs(t, err, internal_errors.ErrAmountOverflow)
+
+	// переполнение uint64, а не только bigint
+	_, err = MulAmount(math.MaxUint64, math.MaxUint32)
+	require.ErrorIs(t, err, internal_errors.ErrAmountOverflow)
+}
+
+func TestAddAmounts(t *testing.T) {
+	sum, err := AddAmounts(MaxAmount-1, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(MaxAmount), sum)
+
+	_, err = AddAmounts(MaxAmount, 1)
+	require.ErrorIs(t, err, internal_errors.ErrAmountOverflow)
+
+	_, err = AddAmounts(math.MaxUint64, 1)
+	require.ErrorIs(t, err, internal_errors.ErrAmountOverflow)
+}
+
+func TestCalculateTotals(t *testing.T) {
+	order := Order{Products: []Product{
+		{UUID: uuid.New(), Quantity: 2, UnitPrice: 15050, Currency: "RUB"},
+		{UUID: uuid.New(), Quantity: 1, UnitPrice: 999, Currency: "RUB"},
+	}}
+
+	require.NoError(t, order.CalculateTotals())
+	require.Equal(t, uint64(31099), order.TotalAmount)
+	require.Zero(t, order.PointsDiscount)
+	require.Equal(t, uint64(31099), order.GrandTotal)
+	require.Equal(t, "RUB", order.Currency)
+
+	order.PaymentType = Points
+	order.WithPoints = 1099
+	require.NoError(t, order.CalculateTotals())
+	require.Equal(t, uint64(31099), order.TotalAmount)
+	require.Equal(t, uint64(1099), order.PointsDiscount)
+	require.Equal(t, uint64(30000), order.GrandTotal)
+
+	// при оплате картой баллы не списываются
+	order.PaymentType = Card
+	require.NoError(t, order.CalculateTotals())
+	require.Zero(t, order.PointsDiscount)
+	require.Equal(t, uint64(31099), order.GrandTotal)
+}
+
+func TestCalculateTotalsError(t *testing.T) {
+	tCases := []struct {
+		name       string
+		products   []Product
+		withPoints int
+		expErr     error
+	}{
+		{
+			name: "mixed_currency",
+			products: []Product{
+				{UUID: uuid.New(), Quantity: 1, UnitPrice: 100, Currency: "RUB"},
+				{UUID: uuid.New(), Quantity: 1, UnitPrice: 100, Currency: "USD"},
+			},
+			expErr: internal_errors.ErrMixedCurrency,
+		},
+		{
+			name:     "invalid_currency",
+			products: []Product{{UUID: uuid.New(), Quantity: 1, UnitPrice: 100, Currency: "usd"}},
+			expErr:   internal_errors.ErrInvalidCurrency,
+		},
+		{
+			name:     "line_overflow",
+			products: []Product{{UUID: uuid.New(), Quantity: math.MaxUint32, UnitPrice: MaxAmount / 2, Currency: "RUB"}},
+			expErr:   internal_errors.ErrAmountOverflow,
+		},
+		{
+			name: "sum_overflow",
+			products: []Product{
+				{UUID: uuid.New(), Quantity: 1, UnitPrice: MaxAmount, Currency: "RUB"},
+				{UUID: uuid.New(), Quantity: 1, UnitPrice: 1, Currency: "RUB"},
+			},
+			expErr: internal_errors.ErrAmountOverflow,
+		},
+		{
+			name:       "points_exceed_total",
+			products:   []Product{{UUID: uuid.New(), Quantity: 2, UnitPrice: 100, Currency: "RUB"}},
+			withPoints: 201,
+			expErr:     internal_errors.ErrIncorrectPoints,
+		},
+		{
+			name:       "negative_points",
+			products:   []Product{{UUID: uuid.New(), Quantity: 2, UnitPrice: 100, Currency: "RUB"}},
+			withPoints: -1,
+			expErr:     internal_errors.ErrIncorrectPoints,
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			order := Order{Products: tCase.products, PaymentType: Points, WithPoints: tCase.withPoints}
+			require.ErrorIs(t, order.CalculateTotals(), tCase.expErr)
+			require.Zero(t, order.TotalAmount)
+		})
+	}
+}
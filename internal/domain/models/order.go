@@ -23,14 +23,29 @@ type Order struct {
 	Products    []Product   `json:"products"`
 	Status      OrderStatus `json:"status"`
 	PaymentType PaymentType `json:"payment_type"`
-	TotalAmount uint64      `json:"total_amount"`
-	WithPoints  int         `json:"with_points"`
+	Currency    string      `json:"currency"`
+	// TotalAmount - сумма строк заказа (subtotal), GrandTotal - сумма к оплате
+	// после скидки по промокоду и списания баллов. Все суммы считаются при
+	// создании и хранятся в заказе.
+	TotalAmount    uint64 `json:"total_amount"`
+	PromoCode      string `json:"promo_code,omitempty"`
+	PromoDiscount  uint64 `json:"promo_discount"`
+	PointsDiscount uint64 `json:"points_discount"`
+	GrandTotal     uint64 `json:"grand_total"`
+	WithPoints     int    `json:"with_points"`
+	// Version - версия строки заказа, растёт с каждым изменением. По ней
+	// проверяется, не устарел ли заказ в кэше.
+	Version int `json:"-"`
 }
 
+// Product - строка заказа. Цена указывается за единицу товара в минорных
+// единицах валюты (копейках, центах), валюта - код ISO 4217.
 type Product struct {
 	UUID      uuid.UUID `json:"product_uuid"`
 	OrderUUID uuid.UUID `json:"order_uuid"`
-	Amount    uint64    `json:"amount"`
+	Quantity  uint32    `json:"quantity"`
+	UnitPrice uint64    `json:"unit_price"`
+	Currency  string    `json:"currency"`
 }
 
 func (oe *Order) UUID() string {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderFilter - условия выборки заказов пользователя. Пустые поля не ограничивают выборку.
+type OrderFilter struct {
+	UserUUID    uuid.UUID
+	Statuses    []OrderStatus
+	PaymentType PaymentType
+	// CreatedFrom включительно, CreatedTo не включительно
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// After - курсор последнего заказа предыдущей страницы
+	After *OrderCursor
+	Limit int
+}
+
+// OrderCursor - позиция заказа в выдаче, отсортированной по (created_at, uuid)
+type OrderCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	OrderUUID uuid.UUID `json:"order_uuid"`
+}
+
+var errInvalidOrderCursor = errors.New("invalid cursor")
+
+// Encode упаковывает курсор в непрозрачную для клиента строку
+func (c OrderCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseOrderCursor разбирает строку, полученную из OrderCursor.Encode
+func ParseOrderCursor(encoded string) (*OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errInvalidOrderCursor
+	}
+
+	var cursor OrderCursor
+	if err = json.Unmarshal(raw, &cursor); err != nil {
+		return nil, errInvalidOrderCursor
+	}
+
+	if cursor.CreatedAt.IsZero() || cursor.OrderUUID == uuid.Nil {
+		return nil, errInvalidOrderCursor
+	}
+
+	return &cursor, nil
+}
+
+// OrdersPage - страница заказов. NextCursor равен nil на последней странице.
+type OrdersPage struct {
+	Orders     []Order
+	NextCursor *OrderCursor
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// MaxPromoCodeLength - наибольшая длина промокода в запросе
+const MaxPromoCodeLength = 64
+
+// CreateOrderRequest - запрос на создание заказа в виде, общем для HTTP и
+// gRPC API. Транспорт только раскладывает свой запрос по полям, проверки
+// выполняет Order.
+type CreateOrderRequest struct {
+	UserUUID    string
+	PaymentType PaymentType
+	Products    []CreateOrderLine
+	WithPoints  int
+	PromoCode   string
+}
+
+// CreateOrderLine - строка запроса на создание заказа
+type CreateOrderLine struct {
+	ProductUUID string
+	Quantity    uint32
+	UnitPrice   uint64
+	Currency    string
+}
+
+// Order проверяет запрос и возвращает заказ в статусе Created с
+// подсчитанными суммами. Валюта, баллы и переполнение сумм проверяются при
+// подсчёте.
+func (r CreateOrderRequest) Order() (Order, error) {
+	userUUID, err := uuid.Parse(r.UserUUID)
+	if err != nil {
+		return Order{}, internal_errors.ErrInvalidUserUUID
+	}
+
+	if r.PaymentType != Card && r.PaymentType != Points {
+		return Order{}, internal_errors.ErrInvalidPaymentType
+	}
+
+	if len(r.Products) == 0 {
+		return Order{}, internal_errors.ErrEmptyProducts
+	}
+
+	if len(r.PromoCode) > MaxPromoCodeLength {
+		return Order{}, internal_errors.ErrInvalidPromoCode
+	}
+
+	// строка заказа адресуется товаром, например при отмене строк, поэтому
+	// товар может встречаться в заказе только один раз
+	products := make([]Product, 0, len(r.Products))
+	seen := make(map[uuid.UUID]bool, len(r.Products))
+	for _, line := range r.Products {
+		productUUID, err := uuid.Parse(line.ProductUUID)
+		if err != nil {
+			return Order{}, fmt.Errorf("%w: %s", internal_errors.ErrInvalidProductUUID, err.Error())
+		}
+
+		if seen[productUUID] {
+			return Order{}, fmt.Errorf("%w: %s", internal_errors.ErrDuplicateProduct, productUUID)
+		}
+		seen[productUUID] = true
+
+		if line.Quantity == 0 {
+			return Order{}, internal_errors.ErrInvalidQuantity
+		}
+
+		if line.UnitPrice == 0 {
+			return Order{}, internal_errors.ErrInvalidUnitPrice
+		}
+
+		products = append(products, Product{
+			UUID:      productUUID,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Currency:  line.Currency,
+		})
+	}
+
+	order := Order{
+		UserUUID:    userUUID,
+		Products:    products,
+		Status:      OrderStatusCreated,
+		PaymentType: r.PaymentType,
+		WithPoints:  r.WithPoints,
+		PromoCode:   r.PromoCode,
+	}
+	if err = order.CalculateTotals(); err != nil {
+		return Order{}, err
+	}
+
+	return order, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import "github.com/google/uuid"
+
+// PointsEntryType - вид движения в журнале баллов
+type PointsEntryType string
+
+const (
+	// PointsEntryCredit - начисление баллов
+	PointsEntryCredit PointsEntryType = "credit"
+	// PointsEntryHold - удержание под созданный заказ
+	PointsEntryHold PointsEntryType = "hold"
+	// PointsEntryCapture - списание удержанных баллов при оплате заказа
+	PointsEntryCapture PointsEntryType = "capture"
+	// PointsEntryRelease - снятие удержания при отмене неоплаченного заказа
+	PointsEntryRelease PointsEntryType = "release"
+	// PointsEntryRefund - возврат списанных баллов при отмене оплаченного заказа
+	PointsEntryRefund PointsEntryType = "refund"
+)
+
+// Статусы удержания баллов под заказ
+const (
+	PointsHoldHeld     = "held"
+	PointsHoldCaptured = "captured"
+	PointsHoldReleased = "released"
+	PointsHoldRefunded = "refunded"
+)
+
+// PointsBalance - баланс баллов пользователя. Held - баллы, удержанные под
+// неоплаченные заказы, тратить можно только Available.
+type PointsBalance struct {
+	UserUUID  uuid.UUID `json:"user_uuid"`
+	Balance   uint64    `json:"balance"`
+	Held      uint64    `json:"held"`
+	Available uint64    `json:"available"`
+}
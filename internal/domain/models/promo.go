@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// PromoKind - вид промокода
+type PromoKind string
+
+const (
+	// PromoPercentage - скидка Percent процентов от суммы строк, с округлением вниз
+	PromoPercentage PromoKind = "percentage"
+	// PromoFixed - скидка Amount в валюте Currency, но не больше суммы строк
+	PromoFixed PromoKind = "fixed"
+	// PromoBuyXGetY - из каждых BuyQuantity + GetQuantity единиц одной строки
+	// GetQuantity единиц бесплатно
+	PromoBuyXGetY PromoKind = "buy_x_get_y"
+)
+
+// PromoCode - промокод. Код действует в интервале [ValidFrom, ValidTo), один
+// пользователь может применить его не больше PerUserLimit раз.
+type PromoCode struct {
+	Code         string
+	Kind         PromoKind
+	Percent      uint32
+	Amount       uint64
+	Currency     string
+	BuyQuantity  uint32
+	GetQuantity  uint32
+	ValidFrom    time.Time
+	ValidTo      time.Time
+	PerUserLimit int
+	// ReadAt - время чтения промокода по часам базы. Срок действия
+	// проверяется по нему, а не по часам сервиса, так же как при расходе
+	// промокода в транзакции создания заказа.
+	ReadAt time.Time
+}
+
+// ActiveAt сообщает, действует ли промокод в момент now
+func (p PromoCode) ActiveAt(now time.Time) bool {
+	return !now.Before(p.ValidFrom) && now.Before(p.ValidTo)
+}
+
+// ApplyPromo применяет промокод к заказу в момент now: считает скидку по
+// строкам, заполняет PromoCode и PromoDiscount и пересчитывает суммы заказа.
+// Промокод, который не даёт скидки на эти строки, не применяется.
+func (oe *Order) ApplyPromo(promo PromoCode, now time.Time) error {
+	if !promo.ActiveAt(now) {
+		return fmt.Errorf("%w: %s", internal_errors.ErrPromoCodeInactive, promo.Code)
+	}
+
+	oe.PromoCode, oe.PromoDiscount = "", 0
+	if err := oe.CalculateTotals(); err != nil {
+		return err
+	}
+
+	discount, err := promo.discount(oe)
+	if err != nil {
+		return err
+	}
+	if discount == 0 {
+		return fmt.Errorf("%w: %s", internal_errors.ErrPromoCodeNotApplicable, promo.Code)
+	}
+
+	oe.PromoCode, oe.PromoDiscount = promo.Code, discount
+
+	return oe.CalculateTotals()
+}
+
+// discount считает скидку по промокоду для заказа с уже посчитанными суммами
+func (p PromoCode) discount(order *Order) (uint64, error) {
+	switch p.Kind {
+	case PromoPercentage:
+		if p.Percent == 0 || p.Percent > 100 {
+			return 0, fmt.Errorf("%w: %s: percent %d", internal_errors.ErrPromoCodeNotApplicable, p.Code, p.Percent)
+		}
+		// TotalAmount не больше MaxAmount, поэтому старшая часть
+		// произведения меньше 100 и деление не переполняется
+		hi, lo := bits.Mul64(order.TotalAmount, uint64(p.Percent))
+		discount, _ := bits.Div64(hi, lo, 100)
+
+		return discount, nil
+	case PromoFixed:
+		if p.Currency != order.Currency {
+			return 0, fmt.Errorf("%w: %s: currency %s, order in %s",
+				internal_errors.ErrPromoCodeNotApplicable, p.Code, p.Currency, order.Currency)
+		}
+
+		return min(p.Amount, order.TotalAmount), nil
+	case PromoBuyXGetY:
+		if p.BuyQuantity == 0 || p.GetQuantity == 0 {
+			return 0, fmt.Errorf("%w: %s: buy %d get %d",
+				internal_errors.ErrPromoCodeNotApplicable, p.Code, p.BuyQuantity, p.GetQuantity)
+		}
+
+		set := uint64(p.BuyQuantity) + uint64(p.GetQuantity)
+
+		var discount uint64
+		for _, product := range order.Products {
+			free := uint64(product.Quantity) / set * uint64(p.GetQuantity)
+
+			// бесплатных единиц не больше, чем в строке, поэтому сумма
+			// не превышает уже посчитанную TotalAmount
+			lineDiscount, err := MulAmount(product.UnitPrice, free)
+			if err != nil {
+				return 0, err
+			}
+			if discount, err = AddAmounts(discount, lineDiscount); err != nil {
+				return 0, err
+			}
+		}
+
+		return discount, nil
+	default:
+		return 0, fmt.Errorf("%w: %s: unknown kind %q", internal_errors.ErrPromoCodeNotApplicable, p.Code, p.Kind)
+	}
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func newPromoOrder() *Order {
+	return &Order{Products: []Product{
+		{UUID: uuid.New(), Quantity: 5, UnitPrice: 1000, Currency: "RUB"},
+		{UUID: uuid.New(), Quantity: 1, UnitPrice: 999, Currency: "RUB"},
+	}}
+}
+
+func TestApplyPromo(t *testing.T) {
+	now := time.Now()
+	window := func(p PromoCode) PromoCode {
+		p.ValidFrom, p.ValidTo = now.Add(-time.Hour), now.Add(time.Hour)
+		return p
+	}
+
+	tCases := []struct {
+		name     string
+		promo    PromoCode
+		discount uint64
+	}{
+		{
+			name:     "percentage",
+			promo:    window(PromoCode{Code: "TEN", Kind: PromoPercentage, Percent: 10}),
+			discount: 599,
+		},
+		{
+			name:     "fixed",
+			promo:    window(PromoCode{Code: "MINUS500", Kind: PromoFixed, Amount: 500, Currency: "RUB"}),
+			discount: 500,
+		},
+		{
+			name:     "fixed_capped_by_total",
+			promo:    window(PromoCode{Code: "HUGE", Kind: PromoFixed, Amount: 1_000_000, Currency: "RUB"}),
+			discount: 5999,
+		},
+		{
+			// 5 единиц первой строки: один полный набор 2+1, одна единица бесплатно
+			name:     "buy_x_get_y",
+			promo:    window(PromoCode{Code: "2PLUS1", Kind: PromoBuyXGetY, BuyQuantity: 2, GetQuantity: 1}),
+			discount: 1000,
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			order := newPromoOrder()
+			require.NoError(t, order.ApplyPromo(tCase.promo, now))
+			require.Equal(t, tCase.promo.Code, order.PromoCode)
+			require.Equal(t, tCase.discount, order.PromoDiscount)
+			require.Equal(t, uint64(5999), order.TotalAmount)
+			require.Equal(t, uint64(5999)-tCase.discount, order.GrandTotal)
+		})
+	}
+}
+
+func TestApplyPromoWithPoints(t *testing.T) {
+	now := time.Now()
+	promo := PromoCode{
+		Code: "MINUS500", Kind: PromoFixed, Amount: 500, Currency: "RUB",
+		ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour),
+	}
+
+	order := newPromoOrder()
+	order.PaymentType = Points
+	order.WithPoints = 5499
+	require.NoError(t, order.ApplyPromo(promo, now))
+	require.Equal(t, uint64(500), order.PromoDiscount)
+	require.Equal(t, uint64(5499), order.PointsDiscount)
+	require.Zero(t, order.GrandTotal)
+
+	// баллы не покрывают скидку по промокоду
+	order = newPromoOrder()
+	order.PaymentType = Points
+	order.WithPoints = 5500
+	require.ErrorIs(t, order.ApplyPromo(promo, now), internal_errors.ErrIncorrectPoints)
+}
+
+func TestApplyPromoError(t *testing.T) {
+	now := time.Now()
+
+	tCases := []struct {
+		name   string
+		promo  PromoCode
+		expErr error
+	}{
+		{
+			name: "not_started",
+			promo: PromoCode{Code: "SOON", Kind: PromoPercentage, Percent: 10,
+				ValidFrom: now.Add(time.Hour), ValidTo: now.Add(2 * time.Hour)},
+			expErr: internal_errors.ErrPromoCodeInactive,
+		},
+		{
+			name: "expired",
+			promo: PromoCode{Code: "OLD", Kind: PromoPercentage, Percent: 10,
+				ValidFrom: now.Add(-2 * time.Hour), ValidTo: now},
+			expErr: internal_errors.ErrPromoCodeInactive,
+		},
+		{
+			name: "fixed_other_currency",
+			promo: PromoCode{Code: "USD5", Kind: PromoFixed, Amount: 500, Currency: "USD",
+				ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour)},
+			expErr: internal_errors.ErrPromoCodeNotApplicable,
+		},
+		{
+			name: "buy_x_get_y_not_enough_quantity",
+			promo: PromoCode{Code: "5PLUS1", Kind: PromoBuyXGetY, BuyQuantity: 5, GetQuantity: 1,
+				ValidFrom: now.Add(-time.Hour), ValidTo: now.Add(time.Hour)},
+			expErr: internal_errors.ErrPromoCodeNotApplicable,
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			order := newPromoOrder()
+			require.ErrorIs(t, order.ApplyPromo(tCase.promo, now), tCase.expErr)
+			require.Empty(t, order.PromoCode)
+			require.Zero(t, order.PromoDiscount)
+		})
+	}
+}
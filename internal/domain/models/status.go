@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// orderStatusTransitions - единая таблица допустимых переходов статуса заказа.
+// Любой переход, которого нет в таблице, считается недопустимым.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusCreated: {OrderStatusPaid, OrderStatusCanceled},
+	OrderStatusPaid:    {OrderStatusDelivered, OrderStatusCanceled},
+}
+
+const (
+	ActorCustomer = "customer"
+	ActorSystem   = "system"
+	ActorPayment  = "payment"
+)
+
+// clientActors - инициаторы, которых клиент API может указать сам.
+// ActorPayment выставляет только consumer событий оплаты, а ActorSystem -
+// только внутренние вызовы сервиса, иначе история статусов не годится для
+// аудита.
+var clientActors = map[string]bool{
+	ActorCustomer: true,
+}
+
+// ClientActor проверяет инициатора изменения, переданного клиентом API.
+// Пустой инициатор означает покупателя.
+func ClientActor(actor string) (string, error) {
+	if actor == "" {
+		return ActorCustomer, nil
+	}
+
+	if !clientActors[actor] {
+		return "", fmt.Errorf("%w: %q", internal_errors.ErrInvalidActor, actor)
+	}
+
+	return actor, nil
+}
+
+// StatusChange - кто и почему меняет статус заказа, попадает в историю статусов
+type StatusChange struct {
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+}
+
+type StatusHistoryEntry struct {
+	OrderUUID  uuid.UUID   `json:"order_uuid"`
+	FromStatus OrderStatus `json:"from_status"`
+	ToStatus   OrderStatus `json:"to_status"`
+	Actor      string      `json:"actor"`
+	Reason     string      `json:"reason"`
+	ChangedAt  time.Time   `json:"changed_at"`
+}
+
+func (s OrderStatus) String() string {
+	switch s {
+	case OrderStatusCreated:
+		return "created"
+	case OrderStatusPaid:
+		return "paid"
+	case OrderStatusDelivered:
+		return "delivered"
+	case OrderStatusCanceled:
+		return "canceled"
+	default:
+		return "undefined"
+	}
+}
+
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s OrderStatus) ValidateTransition(to OrderStatus) error {
+	if s.CanTransitionTo(to) {
+		return nil
+	}
+
+	return &StatusTransitionError{From: s, To: to}
+}
+
+type StatusTransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *StatusTransitionError) Error() string {
+	return fmt.Sprintf("%s: %s -> %s", internal_errors.ErrInvalidStatusTransition, e.From, e.To)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is как на общий
+// ErrInvalidStatusTransition, так и на более конкретную причину
+func (e *StatusTransitionError) Unwrap() []error {
+	errs := []error{internal_errors.ErrInvalidStatusTransition}
+
+	switch e.From {
+	case OrderStatusCanceled:
+		errs = append(errs, internal_errors.ErrOrderAlreadyCanceled)
+	case OrderStatusDelivered:
+		errs = append(errs, internal_errors.ErrOrderAlreadyDelivered)
+	case OrderStatusPaid:
+		if e.To == OrderStatusPaid {
+			errs = append(errs, internal_errors.ErrOrderAlreadyPaid)
+		}
+	case OrderStatusCreated:
+		if e.To == OrderStatusDelivered {
+			errs = append(errs, internal_errors.ErrOrderNotPaid)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tCases := []struct {
+		name string
+		from OrderStatus
+		to   OrderStatus
+	}{
+		{name: "created_to_paid", from: OrderStatusCreated, to: OrderStatusPaid},
+		{name: "created_to_canceled", from: OrderStatusCreated, to: OrderStatusCanceled},
+		{name: "paid_to_delivered", from: OrderStatusPaid, to: OrderStatusDelivered},
+		{name: "paid_to_canceled", from: OrderStatusPaid, to: OrderStatusCanceled},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			require.NoError(t, tCase.from.ValidateTransition(tCase.to))
+		})
+	}
+}
+
+func TestValidateTransitionError(t *testing.T) {
+	tCases := []struct {
+		name   string
+		from   OrderStatus
+		to     OrderStatus
+		expErr error
+	}{
+		{name: "delivered_to_paid", from: OrderStatusDelivered, to: OrderStatusPaid, expErr: internal_errors.ErrOrderAlreadyDelivered},
+		{name: "delivered_to_canceled", from: OrderStatusDelivered, to: OrderStatusCanceled, expErr: internal_errors.ErrOrderAlreadyDelivered},
+		{name: "canceled_to_canceled", from: OrderStatusCanceled, to: OrderStatusCanceled, expErr: internal_errors.ErrOrderAlreadyCanceled},
+		{name: "canceled_to_paid", from: OrderStatusCanceled, to: OrderStatusPaid, expErr: internal_errors.ErrOrderAlreadyCanceled},
+		{name: "paid_to_paid", from: OrderStatusPaid, to: OrderStatusPaid, expErr: internal_errors.ErrOrderAlreadyPaid},
+		{name: "created_to_delivered", from: OrderStatusCreated, to: OrderStatusDelivered, expErr: internal_errors.ErrOrderNotPaid},
+		{name: "undefined_to_created", from: UndefinedStatus, to: OrderStatusCreated, expErr: internal_errors.ErrInvalidStatusTransition},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			err := tCase.from.ValidateTransition(tCase.to)
+			require.Error(t, err)
+			require.ErrorIs(t, err, tCase.expErr)
+			require.ErrorIs(t, err, internal_errors.ErrInvalidStatusTransition)
+
+			var transitionErr *StatusTransitionError
+			require.True(t, errors.As(err, &transitionErr))
+			require.Equal(t, tCase.from, transitionErr.From)
+			require.Equal(t, tCase.to, transitionErr.To)
+		})
+	}
+}
+
+func TestClientActor(t *testing.T) {
+	actor, err := ClientActor("")
+	require.NoError(t, err)
+	require.Equal(t, ActorCustomer, actor)
+
+	actor, err = ClientActor(ActorCustomer)
+	require.NoError(t, err)
+	require.Equal(t, ActorCustomer, actor)
+
+	for _, actor := range []string{ActorPayment, ActorSystem, "admin", "Customer"} {
+		_, err = ClientActor(actor)
+		require.ErrorIs(t, err, internal_errors.ErrInvalidActor, actor)
+	}
+}
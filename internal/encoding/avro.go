@@ -0,0 +1,117 @@
+package encoding
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// AvroEncoder кодирует событие последней версией схемы order_event из реестра.
+// Номер версии уходит в заголовок schema-version.
+type AvroEncoder struct {
+	codec   *goavro.Codec
+	version int
+}
+
+func NewAvroEncoder(registry *FileRegistry) (*AvroEncoder, error) {
+	const op = "encoding.NewAvroEncoder"
+
+	version, schema, err := registry.Latest(orderEventSubject)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: parse schema v%d: %w", op, version, err)
+	}
+
+	return &AvroEncoder{
+		codec:   codec,
+		version: version,
+	}, nil
+}
+
+func (e *AvroEncoder) Encode(event models.OutboxEvent) ([]byte, error) {
+	const op = "encoding.avro"
+
+	order, err := eventOrder(event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	linesCanceled, err := eventLinesCanceled(event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	native := map[string]any{
+		"event_uuid":        event.EventUUID.String(),
+		"event_type":        string(event.EventType),
+		"order_uuid":        event.OrderUUID.String(),
+		"aggregate_version": int64(event.AggregateVersion),
+		"created_at":        event.CreatedAt,
+		"order": map[string]any{
+			"order_uuid":      order.OrderUUID.String(),
+			"user_uuid":       order.UserUUID.String(),
+			"status":          order.Status.String(),
+			"payment_type":    int32(order.PaymentType),
+			"total_amount":    int64(order.TotalAmount),
+			"promo_code":      order.PromoCode,
+			"promo_discount":  int64(order.PromoDiscount),
+			"points_discount": int64(order.PointsDiscount),
+			"grand_total":     int64(order.GrandTotal),
+			"with_points":     int64(order.WithPoints),
+			"products":        avroProducts(order.Products),
+			"currency":        order.Currency,
+		},
+		"lines_canceled": avroLinesCanceled(linesCanceled),
+	}
+
+	bytes, err := e.codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bytes, nil
+}
+
+func avroProducts(products []models.Product) []any {
+	result := make([]any, 0, len(products))
+	for _, product := range products {
+		amount, _ := product.Total()
+		result = append(result, map[string]any{
+			"product_uuid": product.UUID.String(),
+			"amount":       int64(amount),
+			"quantity":     int64(product.Quantity),
+			"unit_price":   int64(product.UnitPrice),
+			"currency":     product.Currency,
+		})
+	}
+
+	return result
+}
+
+// avroLinesCanceled - значение union ["null", LinesCanceled]
+func avroLinesCanceled(cancellation *models.LinesCancellation) any {
+	if cancellation == nil {
+		return nil
+	}
+
+	return goavro.Union("order.v1.LinesCanceled", map[string]any{
+		"products":      avroProducts(cancellation.Products),
+		"refund_amount": int64(cancellation.RefundAmount),
+		"points_refund": int64(cancellation.PointsRefund),
+		"status":        cancellation.Status.String(),
+	})
+}
+
+func (e *AvroEncoder) ContentType() string {
+	return ContentTypeAvro
+}
+
+func (e *AvroEncoder) SchemaVersion() string {
+	return strconv.Itoa(e.version)
+}
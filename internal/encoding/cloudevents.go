@@ -0,0 +1,157 @@
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// Режимы Kafka protocol binding спецификации CloudEvents
+const (
+	ModeBinary     = "binary"
+	ModeStructured = "structured"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	// contentTypeCloudEventsJSON - content-type сообщения в structured-режиме
+	contentTypeCloudEventsJSON = "application/cloudevents+json"
+	// cloudEventsHeaderPrefix - префикс заголовков с атрибутами события в binary-режиме
+	cloudEventsHeaderPrefix = "ce_"
+)
+
+var cloudEventTypes = map[models.EventType]string{
+	models.EventTypeOrderCreated:   "order.created",
+	models.EventTypeOrderPaid:      "order.paid",
+	models.EventTypeOrderDelivered: "order.delivered",
+	models.EventTypeOrderCanceled:  "order.canceled",
+
+	models.EventTypeOrderLinesCanceled: "order.lines_canceled",
+}
+
+// CloudEvents оборачивает событие заказа в конверт CloudEvents 1.0.
+//
+// В binary-режиме атрибуты события уходят в заголовки ce_*, datacontenttype -
+// в заголовок content-type, а значение сообщения - это данные, закодированные
+// Encoder. В structured-режиме значение - JSON-документ события целиком:
+// JSON-данные лежат в data как есть, бинарные (Protobuf, Avro) - в data_base64.
+// Заголовок schema-version проставляется в обоих режимах.
+type CloudEvents struct {
+	encoder Encoder
+	source  string
+	mode    string
+}
+
+func NewCloudEvents(encoder Encoder, source string, mode string) (*CloudEvents, error) {
+	const op = "encoding.NewCloudEvents"
+
+	switch mode {
+	case ModeBinary, ModeStructured:
+	case "":
+		mode = ModeBinary
+	default:
+		return nil, fmt.Errorf("%s: unknown cloudevents mode %q", op, mode)
+	}
+
+	if source == "" {
+		return nil, fmt.Errorf("%s: empty cloudevents source", op)
+	}
+
+	return &CloudEvents{
+		encoder: encoder,
+		source:  source,
+		mode:    mode,
+	}, nil
+}
+
+// NewMessageEncoder собирает кодировщик сообщений Kafka по конфигу: формат
+// данных из Encoding, конверт CloudEvents из CloudEventsSource и CloudEventsMode
+func NewMessageEncoder(cfg config.KafkaConfig) (*CloudEvents, error) {
+	encoder, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCloudEvents(encoder, cfg.CloudEventsSource, cfg.CloudEventsMode)
+}
+
+// Encode возвращает значение сообщения и заголовки, которые нужно к нему добавить
+func (c *CloudEvents) Encode(event models.OutboxEvent) ([]byte, []sarama.RecordHeader, error) {
+	const op = "encoding.CloudEvents.Encode"
+
+	data, err := c.encoder.Encode(event)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schemaVersion := header(HeaderSchemaVersion, c.encoder.SchemaVersion())
+
+	if c.mode == ModeBinary {
+		headers := []sarama.RecordHeader{
+			header(cloudEventsHeaderPrefix+"specversion", cloudEventsSpecVersion),
+			header(cloudEventsHeaderPrefix+"id", event.EventUUID.String()),
+			header(cloudEventsHeaderPrefix+"source", c.source),
+			header(cloudEventsHeaderPrefix+"type", CloudEventType(event.EventType)),
+			header(cloudEventsHeaderPrefix+"subject", event.OrderUUID.String()),
+			header(cloudEventsHeaderPrefix+"time", event.CreatedAt.UTC().Format(time.RFC3339Nano)),
+			header(HeaderContentType, c.encoder.ContentType()),
+			schemaVersion,
+		}
+
+		return data, headers, nil
+	}
+
+	envelope := structuredEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              event.EventUUID.String(),
+		Source:          c.source,
+		Type:            CloudEventType(event.EventType),
+		Subject:         event.OrderUUID.String(),
+		Time:            event.CreatedAt.UTC(),
+		DataContentType: c.encoder.ContentType(),
+	}
+	if c.encoder.ContentType() == ContentTypeJSON {
+		envelope.Data = data
+	} else {
+		envelope.DataBase64 = data
+	}
+
+	value, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return value, []sarama.RecordHeader{header(HeaderContentType, contentTypeCloudEventsJSON), schemaVersion}, nil
+}
+
+// CloudEventType возвращает тип CloudEvents для события заказа, например order.created
+func CloudEventType(eventType models.EventType) string {
+	if ceType, ok := cloudEventTypes[eventType]; ok {
+		return ceType
+	}
+
+	return "order." + strings.ToLower(strings.TrimPrefix(string(eventType), "Order"))
+}
+
+// structuredEvent - JSON-формат события CloudEvents. []byte в DataBase64
+// encoding/json сам кодирует в base64.
+type structuredEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+func header(key, value string) sarama.RecordHeader {
+	return sarama.RecordHeader{Key: []byte(key), Value: []byte(value)}
+}
@@ -0,0 +1,135 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+	"google.golang.org/protobuf/proto"
+)
+
+const testSource = "/order_service"
+
+func headersMap(headers []sarama.RecordHeader) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[string(h.Key)] = string(h.Value)
+	}
+
+	return m
+}
+
+func TestCloudEventsBinary(t *testing.T) {
+	event, _ := newTestEvent(t)
+
+	cloudEvents, err := NewCloudEvents(NewProtobufEncoder(), testSource, ModeBinary)
+	require.NoError(t, err)
+
+	value, headers, err := cloudEvents.Encode(event)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]string{
+		"ce_specversion":    "1.0",
+		"ce_id":             event.EventUUID.String(),
+		"ce_source":         testSource,
+		"ce_type":           "order.paid",
+		"ce_subject":        event.OrderUUID.String(),
+		"ce_time":           "2024-01-02T03:04:05Z",
+		HeaderContentType:   ContentTypeProtobuf,
+		HeaderSchemaVersion: "5",
+	}, headersMap(headers))
+
+	var decoded orderv1.OrderEvent
+	require.NoError(t, proto.Unmarshal(value, &decoded))
+	require.Equal(t, event.EventUUID.String(), decoded.GetEventUuid())
+}
+
+func TestCloudEventsStructuredJSON(t *testing.T) {
+	event, _ := newTestEvent(t)
+
+	cloudEvents, err := NewCloudEvents(NewJSONEncoder(), testSource, ModeStructured)
+	require.NoError(t, err)
+
+	value, headers, err := cloudEvents.Encode(event)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		HeaderContentType:   "application/cloudevents+json",
+		HeaderSchemaVersion: "5",
+	}, headersMap(headers))
+
+	var envelope map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(value, &envelope))
+
+	attr := func(name string) string {
+		var s string
+		require.NoError(t, json.Unmarshal(envelope[name], &s), name)
+		return s
+	}
+
+	require.Equal(t, "1.0", attr("specversion"))
+	require.Equal(t, event.EventUUID.String(), attr("id"))
+	require.Equal(t, testSource, attr("source"))
+	require.Equal(t, "order.paid", attr("type"))
+	require.Equal(t, event.OrderUUID.String(), attr("subject"))
+	require.Equal(t, ContentTypeJSON, attr("datacontenttype"))
+
+	eventTime, err := time.Parse(time.RFC3339Nano, attr("time"))
+	require.NoError(t, err)
+	require.True(t, event.CreatedAt.Equal(eventTime))
+
+	var data models.OutboxEvent
+	require.NoError(t, json.Unmarshal(envelope["data"], &data))
+	require.Equal(t, event.EventUUID, data.EventUUID)
+	require.NotContains(t, envelope, "data_base64")
+}
+
+func TestCloudEventsStructuredBinaryData(t *testing.T) {
+	event, _ := newTestEvent(t)
+
+	cloudEvents, err := NewCloudEvents(NewProtobufEncoder(), testSource, ModeStructured)
+	require.NoError(t, err)
+
+	value, _, err := cloudEvents.Encode(event)
+	require.NoError(t, err)
+
+	var envelope struct {
+		DataContentType string          `json:"datacontenttype"`
+		Data            json.RawMessage `json:"data"`
+		DataBase64      string          `json:"data_base64"`
+	}
+	require.NoError(t, json.Unmarshal(value, &envelope))
+	require.Equal(t, ContentTypeProtobuf, envelope.DataContentType)
+	require.Empty(t, envelope.Data)
+
+	data, err := base64.StdEncoding.DecodeString(envelope.DataBase64)
+	require.NoError(t, err)
+
+	var decoded orderv1.OrderEvent
+	require.NoError(t, proto.Unmarshal(data, &decoded))
+	require.Equal(t, event.OrderUUID.String(), decoded.GetOrderUuid())
+}
+
+func TestNewCloudEventsValidation(t *testing.T) {
+	_, err := NewCloudEvents(NewJSONEncoder(), testSource, "batched")
+	require.Error(t, err)
+
+	_, err = NewCloudEvents(NewJSONEncoder(), "", ModeBinary)
+	require.Error(t, err)
+
+	cloudEvents, err := NewCloudEvents(NewJSONEncoder(), testSource, "")
+	require.NoError(t, err)
+	require.Equal(t, ModeBinary, cloudEvents.mode)
+}
+
+func TestCloudEventType(t *testing.T) {
+	require.Equal(t, "order.created", CloudEventType(models.EventTypeOrderCreated))
+	require.Equal(t, "order.delivered", CloudEventType(models.EventTypeOrderDelivered))
+	require.Equal(t, "order.canceled", CloudEventType(models.EventTypeOrderCanceled))
+	require.Equal(t, "order.lines_canceled", CloudEventType(models.EventTypeOrderLinesCanceled))
+	require.Equal(t, "order.refunded", CloudEventType("OrderRefunded"))
+}
@@ -0,0 +1,96 @@
+// Package encoding сериализует события заказа для Kafka.
+//
+// Формат выбирается в KafkaConfig.Encoding. Каждый кодировщик сообщает
+// content-type и версию схемы, которые проставляются в заголовки сообщения,
+// чтобы потребители могли выбрать нужный декодер. Закодированное событие
+// публикуется в конверте CloudEvents, см. CloudEvents.
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+const (
+	FormatJSON     = "json"
+	FormatProtobuf = "protobuf"
+	FormatAvro     = "avro"
+)
+
+// Заголовки Kafka, которые проставляются на каждое сообщение.
+//
+// schema-version одинакова во всех форматах и растёт с каждым изменением
+// контракта события, в том числе с добавлением поля: в JSON и Protobuf это
+// константа кодировщика, в Avro - новый файл схемы в реестре.
+const (
+	HeaderContentType   = "content-type"
+	HeaderSchemaVersion = "schema-version"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+)
+
+// orderEventSubject - имя схемы события заказа в реестре схем
+const orderEventSubject = "order_event"
+
+type Encoder interface {
+	Encode(event models.OutboxEvent) ([]byte, error)
+	ContentType() string
+	SchemaVersion() string
+}
+
+// New возвращает кодировщик, выбранный в конфиге. Для Avro схема берётся из
+// файлового реестра в SchemaRegistryDir.
+func New(cfg config.KafkaConfig) (Encoder, error) {
+	const op = "encoding.New"
+
+	switch cfg.Encoding {
+	case FormatJSON, "":
+		return NewJSONEncoder(), nil
+	case FormatProtobuf:
+		return NewProtobufEncoder(), nil
+	case FormatAvro:
+		encoder, err := NewAvroEncoder(NewFileRegistry(cfg.SchemaRegistryDir))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return encoder, nil
+	default:
+		return nil, fmt.Errorf("%s: unknown encoding %q", op, cfg.Encoding)
+	}
+}
+
+// eventOrder разбирает снимок заказа из payload события
+func eventOrder(event models.OutboxEvent) (models.Order, error) {
+	var order models.Order
+	if len(event.Payload) == 0 {
+		return order, nil
+	}
+
+	if err := json.Unmarshal(event.Payload, &order); err != nil {
+		return order, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	return order, nil
+}
+
+// eventLinesCanceled разбирает отменённые строки из payload события
+// OrderLinesCanceled, у остальных событий их нет
+func eventLinesCanceled(event models.OutboxEvent) (*models.LinesCancellation, error) {
+	if event.EventType != models.EventTypeOrderLinesCanceled || len(event.Payload) == 0 {
+		return nil, nil
+	}
+
+	var payload models.LinesCanceledEvent
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("unmarshal payload: %w", err)
+	}
+
+	return &payload.LinesCanceled, nil
+}
@@ -0,0 +1,249 @@
+package encoding
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+	"google.golang.org/protobuf/proto"
+)
+
+const schemasDir = "../../schemas"
+
+func newTestEvent(t *testing.T) (models.OutboxEvent, models.Order) {
+	t.Helper()
+
+	order := models.Order{
+		OrderUUID:      uuid.New(),
+		UserUUID:       uuid.New(),
+		Status:         models.OrderStatusPaid,
+		PaymentType:    models.Points,
+		Currency:       "RUB",
+		TotalAmount:    300,
+		PromoCode:      "TEN",
+		PromoDiscount:  30,
+		PointsDiscount: 50,
+		GrandTotal:     220,
+		WithPoints:     50,
+		Products: []models.Product{
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: 100, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 2, UnitPrice: 100, Currency: "RUB"},
+		},
+	}
+
+	payload, err := json.Marshal(order)
+	require.NoError(t, err)
+
+	return models.OutboxEvent{
+		EventUUID:        uuid.New(),
+		EventType:        models.EventTypeOrderPaid,
+		OrderUUID:        order.OrderUUID,
+		AggregateVersion: 2,
+		CreatedAt:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Payload:          payload,
+	}, order
+}
+
+func TestJSONEncoder(t *testing.T) {
+	event, _ := newTestEvent(t)
+	encoder := NewJSONEncoder()
+
+	bytes, err := encoder.Encode(event)
+	require.NoError(t, err)
+
+	var decoded models.OutboxEvent
+	require.NoError(t, json.Unmarshal(bytes, &decoded))
+	require.Equal(t, event.EventUUID, decoded.EventUUID)
+	require.Equal(t, event.EventType, decoded.EventType)
+	require.JSONEq(t, string(event.Payload), string(decoded.Payload))
+
+	require.Equal(t, ContentTypeJSON, encoder.ContentType())
+	require.Equal(t, "5", encoder.SchemaVersion())
+}
+
+func TestProtobufEncoder(t *testing.T) {
+	event, order := newTestEvent(t)
+	encoder := NewProtobufEncoder()
+
+	bytes, err := encoder.Encode(event)
+	require.NoError(t, err)
+
+	var decoded orderv1.OrderEvent
+	require.NoError(t, proto.Unmarshal(bytes, &decoded))
+	require.Equal(t, event.EventUUID.String(), decoded.GetEventUuid())
+	require.Equal(t, string(event.EventType), decoded.GetEventType())
+	require.Equal(t, int64(event.AggregateVersion), decoded.GetAggregateVersion())
+	require.True(t, event.CreatedAt.Equal(decoded.GetCreatedAt().AsTime()))
+	require.Equal(t, orderv1.OrderStatus_ORDER_STATUS_PAID, decoded.GetOrder().GetStatus())
+	require.Equal(t, orderv1.PaymentType_PAYMENT_TYPE_POINTS, decoded.GetOrder().GetPaymentType())
+	require.Equal(t, order.TotalAmount, decoded.GetOrder().GetTotalAmount())
+	require.Equal(t, order.PromoCode, decoded.GetOrder().GetPromoCode())
+	require.Equal(t, order.PromoDiscount, decoded.GetOrder().GetPromoDiscount())
+	require.Equal(t, order.PointsDiscount, decoded.GetOrder().GetPointsDiscount())
+	require.Equal(t, order.GrandTotal, decoded.GetOrder().GetGrandTotal())
+	require.Len(t, decoded.GetOrder().GetProducts(), 2)
+	require.Equal(t, order.Products[1].UUID.String(), decoded.GetOrder().GetProducts()[1].GetProductUuid())
+	require.Equal(t, uint64(200), decoded.GetOrder().GetProducts()[1].GetAmount())
+	require.Equal(t, uint32(2), decoded.GetOrder().GetProducts()[1].GetQuantity())
+	require.Equal(t, "RUB", decoded.GetOrder().GetCurrency())
+
+	require.Equal(t, ContentTypeProtobuf, encoder.ContentType())
+	require.Equal(t, "5", encoder.SchemaVersion())
+}
+
+func TestAvroEncoder(t *testing.T) {
+	event, order := newTestEvent(t)
+
+	encoder, err := NewAvroEncoder(NewFileRegistry(schemasDir))
+	require.NoError(t, err)
+
+	bytes, err := encoder.Encode(event)
+	require.NoError(t, err)
+
+	_, schema, err := NewFileRegistry(schemasDir).Latest(orderEventSubject)
+	require.NoError(t, err)
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(bytes)
+	require.NoError(t, err)
+
+	record := native.(map[string]any)
+	require.Equal(t, event.EventUUID.String(), record["event_uuid"])
+	require.Equal(t, string(event.EventType), record["event_type"])
+	require.True(t, event.CreatedAt.Equal(record["created_at"].(time.Time)))
+
+	orderRecord := record["order"].(map[string]any)
+	require.Equal(t, "paid", orderRecord["status"])
+	require.Equal(t, int64(order.TotalAmount), orderRecord["total_amount"])
+	require.Equal(t, int64(order.GrandTotal), orderRecord["grand_total"])
+	require.Equal(t, "RUB", orderRecord["currency"])
+	require.Equal(t, "TEN", orderRecord["promo_code"])
+	require.Equal(t, int64(order.PromoDiscount), orderRecord["promo_discount"])
+	require.Len(t, orderRecord["products"], 2)
+
+	product := orderRecord["products"].([]any)[1].(map[string]any)
+	require.Equal(t, int64(200), product["amount"])
+	require.Equal(t, int64(100), product["unit_price"])
+
+	require.Equal(t, ContentTypeAvro, encoder.ContentType())
+	require.Equal(t, "5", encoder.SchemaVersion())
+}
+
+// TestSchemaVersionsMatch проверяет, что изменение контракта подняло версию
+// во всех форматах: новая схема Avro без новой версии JSON и Protobuf
+// ломает тест
+func TestSchemaVersionsMatch(t *testing.T) {
+	avro, err := NewAvroEncoder(NewFileRegistry(schemasDir))
+	require.NoError(t, err)
+
+	require.Equal(t, avro.SchemaVersion(), NewJSONEncoder().SchemaVersion())
+	require.Equal(t, avro.SchemaVersion(), NewProtobufEncoder().SchemaVersion())
+}
+
+func TestEncodeLinesCanceled(t *testing.T) {
+	event, order := newTestEvent(t)
+
+	canceled := order.Products[0]
+	order.Products = order.Products[1:]
+	payload, err := json.Marshal(models.LinesCanceledEvent{
+		Order: order,
+		LinesCanceled: models.LinesCancellation{
+			OrderUUID:    order.OrderUUID,
+			Products:     []models.Product{canceled},
+			RefundAmount: 90,
+			Status:       models.OrderStatusPaid,
+		},
+	})
+	require.NoError(t, err)
+	event.EventType = models.EventTypeOrderLinesCanceled
+	event.Payload = payload
+
+	bytes, err := NewProtobufEncoder().Encode(event)
+	require.NoError(t, err)
+
+	var decoded orderv1.OrderEvent
+	require.NoError(t, proto.Unmarshal(bytes, &decoded))
+	require.Len(t, decoded.GetOrder().GetProducts(), 1)
+	require.Equal(t, uint64(90), decoded.GetLinesCanceled().GetRefundAmount())
+	require.Equal(t, orderv1.OrderStatus_ORDER_STATUS_PAID, decoded.GetLinesCanceled().GetStatus())
+	require.Equal(t, canceled.UUID.String(), decoded.GetLinesCanceled().GetProducts()[0].GetProductUuid())
+
+	encoder, err := NewAvroEncoder(NewFileRegistry(schemasDir))
+	require.NoError(t, err)
+	bytes, err = encoder.Encode(event)
+	require.NoError(t, err)
+
+	_, schema, err := NewFileRegistry(schemasDir).Latest(orderEventSubject)
+	require.NoError(t, err)
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	native, _, err := codec.NativeFromBinary(bytes)
+	require.NoError(t, err)
+
+	union := native.(map[string]any)["lines_canceled"].(map[string]any)
+	linesCanceled := union["order.v1.LinesCanceled"].(map[string]any)
+	require.Equal(t, int64(90), linesCanceled["refund_amount"])
+	require.Equal(t, "paid", linesCanceled["status"])
+	require.Len(t, linesCanceled["products"], 1)
+
+	// у остальных событий отменённых строк нет
+	event, _ = newTestEvent(t)
+	bytes, err = NewProtobufEncoder().Encode(event)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(bytes, &decoded))
+	require.Nil(t, decoded.GetLinesCanceled())
+}
+
+func TestFileRegistryLatest(t *testing.T) {
+	dir := t.TempDir()
+	subjectDir := filepath.Join(dir, orderEventSubject)
+	require.NoError(t, os.MkdirAll(subjectDir, 0o755))
+
+	for name, content := range map[string]string{
+		"v1.avsc":   `"string"`,
+		"v10.avsc":  `"long"`,
+		"v2.avsc":   `"int"`,
+		"README.md": "not a schema",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(subjectDir, name), []byte(content), 0o644))
+	}
+
+	registry := NewFileRegistry(dir)
+
+	version, schema, err := registry.Latest(orderEventSubject)
+	require.NoError(t, err)
+	require.Equal(t, 10, version)
+	require.Equal(t, `"long"`, schema)
+
+	_, _, err = registry.Latest("unknown")
+	require.ErrorIs(t, err, ErrSchemaNotFound)
+
+	_, err = registry.Schema(orderEventSubject, 3)
+	require.ErrorIs(t, err, ErrSchemaNotFound)
+}
+
+func TestNew(t *testing.T) {
+	for format, contentType := range map[string]string{
+		"":             ContentTypeJSON,
+		FormatJSON:     ContentTypeJSON,
+		FormatProtobuf: ContentTypeProtobuf,
+		FormatAvro:     ContentTypeAvro,
+	} {
+		encoder, err := New(config.KafkaConfig{Encoding: format, SchemaRegistryDir: schemasDir})
+		require.NoError(t, err, format)
+		require.Equal(t, contentType, encoder.ContentType(), format)
+	}
+
+	_, err := New(config.KafkaConfig{Encoding: "xml"})
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// jsonSchemaVersion - версия JSON-представления models.OutboxEvent:
+//   - 2: у строк заказа вместо amount есть quantity, unit_price и currency;
+//   - 3: у заказа есть points_discount и grand_total;
+//   - 4: у заказа есть promo_code и promo_discount;
+//   - 5: у события OrderLinesCanceled есть lines_canceled.
+const jsonSchemaVersion = "5"
+
+// JSONEncoder публикует событие в том же виде, в каком оно хранится в outbox
+type JSONEncoder struct{}
+
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{}
+}
+
+func (e *JSONEncoder) Encode(event models.OutboxEvent) ([]byte, error) {
+	bytes, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("encoding.json: %w", err)
+	}
+
+	return bytes, nil
+}
+
+func (e *JSONEncoder) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (e *JSONEncoder) SchemaVersion() string {
+	return jsonSchemaVersion
+}
@@ -0,0 +1,112 @@
+package encoding
+
+import (
+	"fmt"
+
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding/pb/orderv1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protobufSchemaVersion - версия контракта order_event.proto, совпадает с
+// jsonSchemaVersion. Изменения добавляют поля, поэтому пакет остаётся order.v1.
+const protobufSchemaVersion = "5"
+
+// ProtobufEncoder кодирует событие в orderv1.OrderEvent.
+//
+// Go-код контракта генерируется из api/proto/order/v1/order_event.proto.
+type ProtobufEncoder struct{}
+
+func NewProtobufEncoder() *ProtobufEncoder {
+	return &ProtobufEncoder{}
+}
+
+func (e *ProtobufEncoder) Encode(event models.OutboxEvent) ([]byte, error) {
+	const op = "encoding.protobuf"
+
+	order, err := eventOrder(event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	linesCanceled, err := eventLinesCanceled(event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	msg := &orderv1.OrderEvent{
+		EventUuid:        event.EventUUID.String(),
+		EventType:        string(event.EventType),
+		OrderUuid:        event.OrderUUID.String(),
+		AggregateVersion: int64(event.AggregateVersion),
+		CreatedAt:        timestamppb.New(event.CreatedAt),
+		Order:            OrderProto(order),
+		LinesCanceled:    linesCanceledProto(linesCanceled),
+	}
+
+	bytes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bytes, nil
+}
+
+func (e *ProtobufEncoder) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (e *ProtobufEncoder) SchemaVersion() string {
+	return protobufSchemaVersion
+}
+
+// OrderProto переводит заказ в сообщение контракта order.v1. Им же заказ
+// отдаётся в gRPC API.
+func OrderProto(order models.Order) *orderv1.Order {
+	return &orderv1.Order{
+		OrderUuid:      order.OrderUUID.String(),
+		UserUuid:       order.UserUUID.String(),
+		Status:         orderv1.OrderStatus(order.Status),
+		PaymentType:    orderv1.PaymentType(order.PaymentType),
+		TotalAmount:    order.TotalAmount,
+		PromoCode:      order.PromoCode,
+		PromoDiscount:  order.PromoDiscount,
+		PointsDiscount: order.PointsDiscount,
+		GrandTotal:     order.GrandTotal,
+		WithPoints:     int64(order.WithPoints),
+		Products:       productsProto(order.Products),
+		Currency:       order.Currency,
+	}
+}
+
+func linesCanceledProto(cancellation *models.LinesCancellation) *orderv1.LinesCanceled {
+	if cancellation == nil {
+		return nil
+	}
+
+	return &orderv1.LinesCanceled{
+		Products:     productsProto(cancellation.Products),
+		RefundAmount: cancellation.RefundAmount,
+		PointsRefund: cancellation.PointsRefund,
+		Status:       orderv1.OrderStatus(cancellation.Status),
+	}
+}
+
+func productsProto(products []models.Product) []*orderv1.Product {
+	result := make([]*orderv1.Product, 0, len(products))
+	for _, product := range products {
+		// строки прочитаны из базы или прошли CalculateTotal при создании,
+		// поэтому их стоимость не переполняется
+		amount, _ := product.Total()
+		result = append(result, &orderv1.Product{
+			ProductUuid: product.UUID.String(),
+			Amount:      amount,
+			Quantity:    product.Quantity,
+			UnitPrice:   product.UnitPrice,
+			Currency:    product.Currency,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,81 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// FileRegistry - локальная замена schema registry: схемы лежат в файлах
+// <dir>/<subject>/v<version>.avsc, новая версия схемы добавляется новым файлом.
+type FileRegistry struct {
+	dir string
+}
+
+func NewFileRegistry(dir string) *FileRegistry {
+	return &FileRegistry{dir: dir}
+}
+
+// Latest возвращает последнюю версию схемы subject
+func (r *FileRegistry) Latest(subject string) (version int, schema string, err error) {
+	const op = "encoding.FileRegistry.Latest"
+
+	entries, err := os.ReadDir(filepath.Join(r.dir, subject))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, "", fmt.Errorf("%s: %s: %w", op, subject, ErrSchemaNotFound)
+		}
+		return 0, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, entry := range entries {
+		entryVersion, ok := schemaFileVersion(entry.Name())
+		if ok && entryVersion > version {
+			version = entryVersion
+		}
+	}
+
+	if version == 0 {
+		return 0, "", fmt.Errorf("%s: %s: %w", op, subject, ErrSchemaNotFound)
+	}
+
+	schema, err = r.Schema(subject, version)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return version, schema, nil
+}
+
+// Schema возвращает схему subject конкретной версии
+func (r *FileRegistry) Schema(subject string, version int) (string, error) {
+	const op = "encoding.FileRegistry.Schema"
+
+	bytes, err := os.ReadFile(filepath.Join(r.dir, subject, fmt.Sprintf("v%d.avsc", version)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s: %s v%d: %w", op, subject, version, ErrSchemaNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return string(bytes), nil
+}
+
+func schemaFileVersion(name string) (int, bool) {
+	if !strings.HasPrefix(name, "v") || !strings.HasSuffix(name, ".avsc") {
+		return 0, false
+	}
+
+	version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "v"), ".avsc"))
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+
+	return version, true
+}
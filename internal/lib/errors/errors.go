@@ -7,4 +7,35 @@ var (
 	ErrOrderNotFound         = errors.New("order not found")
 	ErrOrderAlreadyCanceled  = errors.New("order already canceled")
 	ErrOrderAlreadyDelivered = errors.New("order already delivered")
+	ErrOrderAlreadyPaid      = errors.New("order already paid")
+	ErrOrderNotPaid          = errors.New("order is not paid")
+	ErrOrderLineNotFound     = errors.New("order line not found")
+
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrInvalidActor            = errors.New("invalid actor")
+
+	ErrInvalidUserUUID    = errors.New("invalid user_uuid")
+	ErrInvalidProductUUID = errors.New("invalid product_uuid")
+	ErrInvalidPaymentType = errors.New("invalid payment_type")
+	ErrInvalidQuantity    = errors.New("invalid quantity")
+	ErrInvalidUnitPrice   = errors.New("invalid unit_price")
+	ErrInvalidPromoCode   = errors.New("invalid promo_code")
+	ErrEmptyProducts      = errors.New("products can't be empty")
+	ErrDuplicateProduct   = errors.New("duplicate product_uuid")
+
+	ErrInvalidCurrency = errors.New("invalid currency")
+	ErrMixedCurrency   = errors.New("order lines have different currencies")
+	ErrAmountOverflow  = errors.New("order amount is too large")
+	ErrIncorrectPoints = errors.New("incorrect points value")
+
+	ErrInsufficientPoints = errors.New("not enough points")
+
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeInactive      = errors.New("promo code is not active")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to the order")
+	ErrPromoCodeLimitReached  = errors.New("promo code usage limit reached")
+
+	ErrIdempotencyKeyReused     = errors.New("idempotency key is already used for another request")
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is in progress")
+	ErrIdempotencyKeyLockLost   = errors.New("idempotency key was taken over by another request")
 )
@@ -0,0 +1,6 @@
+// Package outbox хранит общие для репозитория и релея соглашения об outbox.
+package outbox
+
+// NotifyChannel - канал LISTEN/NOTIFY, в который репозиторий сообщает о новых
+// событиях в outbox, а релей слушает его, чтобы не ждать очередного опроса
+const NotifyChannel = "outbox_events"
@@ -3,7 +3,6 @@ package outbox_producer
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/IBM/sarama"
@@ -11,39 +10,76 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 	"log/slog"
+	"strconv"
+	"time"
 )
 
-type OutboxProducer struct {
-	producer    sarama.SyncProducer
-	db          *sqlx.DB
-	kafkaConfig config.KafkaConfig
-	log         *slog.Logger
+// messageEncoder возвращает значение сообщения и заголовки конверта события
+type messageEncoder interface {
+	Encode(event models.OutboxEvent) ([]byte, []sarama.RecordHeader, error)
 }
 
-type outboxMessage struct {
-	EventUUID uuid.UUID `json:"event_uuid"`
-	OrderUUID uuid.UUID `json:"order_uuid"`
+type OutboxProducer struct {
+	producer     sarama.SyncProducer
+	db           *sqlx.DB
+	kafkaConfig  config.KafkaConfig
+	outboxConfig config.OutboxConfig
+	encoder      messageEncoder
+	log          *slog.Logger
 }
 
+const (
+	headerEventUUID        = "event_uuid"
+	headerEventType        = "event_type"
+	headerOrderUUID        = "order_uuid"
+	headerAggregateVersion = "aggregate_version"
+)
+
 func New(
 	producer sarama.SyncProducer,
 	db *sqlx.DB,
 	kafkaConfig config.KafkaConfig,
+	outboxConfig config.OutboxConfig,
+	encoder messageEncoder,
 	log *slog.Logger,
 ) *OutboxProducer {
 	return &OutboxProducer{
-		producer:    producer,
-		db:          db,
-		kafkaConfig: kafkaConfig,
-		log:         log,
+		producer:     producer,
+		db:           db,
+		kafkaConfig:  kafkaConfig,
+		outboxConfig: outboxConfig,
+		encoder:      encoder,
+		log:          log,
 	}
 }
 
-const messageSendLimit = 100
-
-func (op *OutboxProducer) ProduceMessages(ctx context.Context) (err error) {
-	tx, err := op.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+// ProduceMessages публикует одну пачку неотправленных событий и возвращает количество
+// опубликованных.
+//
+// Строки пачки захватываются через FOR UPDATE SKIP LOCKED и остаются заблокированными
+// до коммита, поэтому несколько экземпляров релея могут работать одновременно:
+// каждый забирает свои строки, пропуская уже захваченные другими, и ни одно
+// событие не публикуется дважды.
+//
+// Сообщения публикуются с ключом order_uuid, поэтому все события заказа попадают в
+// одну партицию. Порядок событий одного заказа сохраняется: они выбираются в порядке
+// id, а после первой неудачной отправки более поздние события этого заказа
+// откладываются до следующей пачки.
+//
+// Неудачная отправка не откатывает пачку: успешно опубликованные события
+// отмечаются отправленными, а у неудачных увеличивается attempts и выставляется
+// next_attempt_at с экспоненциальной паузой. После MaxAttempts попыток событие
+// переносится в outbox_dead_letter, чтобы не блокировать очередь других заказов.
+// Более поздние события того же заказа после этого не публикуются, пока событие
+// из outbox_dead_letter не разберут вручную, иначе порядок событий заказа нарушится.
+func (op *OutboxProducer) ProduceMessages(ctx context.Context) (sent int, err error) {
+	tx, err := op.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		op.log.Error("outbox_producer", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
 	defer func() {
 		if err != nil {
 			if rollBackErr := tx.Rollback(); rollBackErr != nil {
@@ -53,67 +89,330 @@ func (op *OutboxProducer) ProduceMessages(ctx context.Context) (err error) {
 		}
 	}()
 
+	events, err := op.claimEvents(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(events) == 0 {
+		return 0, tx.Commit()
+	}
+
+	published, failed := op.publish(events)
+
+	for _, event := range events {
+		if sendErr, ok := failed[event.EventUUID]; ok {
+			if err = op.registerFailure(ctx, tx, event, sendErr); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if len(published) == 0 {
+		return 0, tx.Commit()
+	}
+
+	// Отметка об отправке ставится только после подтверждения от брокера. Если
+	// коммит не пройдёт, события будут опубликованы повторно - доставка at-least-once,
+	// дубликаты отсекаются потребителями по event_uuid.
+	const outboxUpdateQuery = `UPDATE "outbox" SET send = TRUE, sent_at = now() WHERE event_uuid = ANY($1)`
+
+	if _, err = tx.ExecContext(ctx, outboxUpdateQuery, pq.Array(published)); err != nil {
+		op.log.Error("outbox_producer", slog.String("error", err.Error()))
+		return 0, fmt.Errorf("update outbox: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return len(published), nil
+}
+
+// claimEvents блокирует очередную пачку неотправленных событий, время повторной
+// отправки которых уже наступило. События заказа, у которого есть более раннее
+// неотправленное событие вне пачки (его держит другой экземпляр релея или оно ждёт
+// повторной попытки), в пачку не попадают, иначе они могли бы обогнать его.
+// События заказа, одно из событий которого лежит в outbox_dead_letter, не
+// выбираются совсем, пока его не разберут вручную.
+func (op *OutboxProducer) claimEvents(ctx context.Context, tx *sql.Tx) ([]models.OutboxEvent, error) {
 	const outboxSelectQuery = `
-								SELECT event_uuid, order_uuid 
-									FROM "outbox"
-									WHERE send = FALSE
-									ORDER BY order_uuid
-									LIMIT 100
+								SELECT id, event_uuid, order_uuid, event_type, payload, created_at, aggregate_version, attempts
+									FROM "outbox" o
+									WHERE send = FALSE AND next_attempt_at <= now()
+										AND NOT EXISTS (
+											SELECT 1 FROM "outbox_dead_letter" d WHERE d.order_uuid = o.order_uuid
+										)
+									ORDER BY id
+									LIMIT $1
+									FOR UPDATE SKIP LOCKED
 								`
 
-	rows, err := tx.QueryContext(ctx, outboxSelectQuery)
+	rows, err := tx.QueryContext(ctx, outboxSelectQuery, op.outboxConfig.BatchSize)
 	if err != nil {
 		op.log.Error("outbox_producer", slog.String("error", err.Error()))
-		return fmt.Errorf("query outbox: %w", err)
+		return nil, fmt.Errorf("query outbox: %w", err)
 	}
 	defer func(rows *sql.Rows) {
-		err = rows.Close()
-		if err != nil {
-			op.log.Error("outbox_producer", slog.String("error", err.Error()))
+		if closeErr := rows.Close(); closeErr != nil {
+			op.log.Error("outbox_producer", slog.String("error", closeErr.Error()))
 		}
 	}(rows)
 
-	var eventUUIDs []uuid.UUID
-	saramaMessages := make([]*sarama.ProducerMessage, 0, messageSendLimit)
+	events := make([]models.OutboxEvent, 0, op.outboxConfig.BatchSize)
+	firstIDs := make(map[uuid.UUID]int64)
+	var orderUUIDs []uuid.UUID
 
 	for rows.Next() {
-		msg := outboxMessage{}
-		if err = rows.Scan(&msg.EventUUID, &msg.OrderUUID); err != nil {
+		var payload []byte
+		event := models.OutboxEvent{}
+		if err = rows.Scan(
+			&event.ID, &event.EventUUID, &event.OrderUUID, &event.EventType,
+			&payload, &event.CreatedAt, &event.AggregateVersion, &event.Attempts,
+		); err != nil {
 			op.log.Error("outbox_producer", slog.String("error", err.Error()))
-			return fmt.Errorf("scan outbox: %w", err)
+			return nil, fmt.Errorf("scan outbox: %w", err)
 		}
+		event.Payload = payload
 
-		bytes, err := json.Marshal(msg)
-		if err != nil {
-			op.log.Error("outbox_producer", slog.String("error", err.Error()))
-			return fmt.Errorf("marshal outbox: %w", err)
+		if _, ok := firstIDs[event.OrderUUID]; !ok {
+			firstIDs[event.OrderUUID] = event.ID
+			orderUUIDs = append(orderUUIDs, event.OrderUUID)
 		}
 
-		saramaMessages = append(saramaMessages, &sarama.ProducerMessage{
-			Topic: op.kafkaConfig.OrderEventTopic,
-			Value: sarama.ByteEncoder(bytes),
-		})
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		op.log.Error("outbox_producer", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("iterate outbox: %w", err)
+	}
 
-		eventUUIDs = append(eventUUIDs, msg.EventUUID)
+	if len(events) == 0 {
+		return nil, nil
 	}
 
-	const outboxUpdateQuery = `UPDATE "outbox" SET send = TRUE WHERE event_uuid = ANY($1)`
+	const earliestQuery = `
+							SELECT order_uuid, min(id)
+								FROM "outbox"
+								WHERE send = FALSE AND order_uuid = ANY($1)
+								GROUP BY order_uuid
+						`
 
-	// Сначала обновляем данные в таблице, чтобы в случае если база упадёт,
-	// транзакция отменилась, и мы не отправили сообщения в топик
-	//
-	// При обратной последовательности может произойти так, что, сначала
-	// отправляя сообщения в топик, а после обновляя данные в таблице, мы
-	// база может упасть, а сообщения уже будут отправлены.
-	if _, err = tx.ExecContext(ctx, outboxUpdateQuery, pq.Array(eventUUIDs)); err != nil {
+	earliestRows, err := tx.QueryContext(ctx, earliestQuery, pq.Array(orderUUIDs))
+	if err != nil {
 		op.log.Error("outbox_producer", slog.String("error", err.Error()))
-		return fmt.Errorf("update outbox: %w", err)
+		return nil, fmt.Errorf("query earliest events: %w", err)
 	}
+	defer func(rows *sql.Rows) {
+		if closeErr := rows.Close(); closeErr != nil {
+			op.log.Error("outbox_producer", slog.String("error", closeErr.Error()))
+		}
+	}(earliestRows)
 
-	if err = op.producer.SendMessages(saramaMessages); err != nil {
+	blocked := make(map[uuid.UUID]bool)
+	for earliestRows.Next() {
+		var (
+			orderUUID  uuid.UUID
+			earliestID int64
+		)
+		if err = earliestRows.Scan(&orderUUID, &earliestID); err != nil {
+			op.log.Error("outbox_producer", slog.String("error", err.Error()))
+			return nil, fmt.Errorf("scan earliest events: %w", err)
+		}
+		if earliestID < firstIDs[orderUUID] {
+			blocked[orderUUID] = true
+		}
+	}
+	if err = earliestRows.Err(); err != nil {
 		op.log.Error("outbox_producer", slog.String("error", err.Error()))
-		return fmt.Errorf("send messages: %w", err)
+		return nil, fmt.Errorf("iterate earliest events: %w", err)
+	}
+
+	if len(blocked) == 0 {
+		return events, nil
 	}
 
-	return tx.Commit()
+	claimed := events[:0]
+	for _, event := range events {
+		if !blocked[event.OrderUUID] {
+			claimed = append(claimed, event)
+		}
+	}
+
+	return claimed, nil
+}
+
+// publish отправляет события волнами: в волну k попадает k-е событие каждого заказа,
+// поэтому следующее событие заказа уходит только после подтверждения предыдущего.
+// Если событие заказа не удалось отправить, остальные его события в этой пачке не
+// публикуются. Возвращает event_uuid опубликованных событий и ошибки тех событий,
+// отправить которые не удалось.
+func (op *OutboxProducer) publish(events []models.OutboxEvent) (published []uuid.UUID, failed map[uuid.UUID]error) {
+	failed = make(map[uuid.UUID]error)
+
+	var orderUUIDs []uuid.UUID
+	byOrder := make(map[uuid.UUID][]models.OutboxEvent)
+	for _, event := range events {
+		if _, ok := byOrder[event.OrderUUID]; !ok {
+			orderUUIDs = append(orderUUIDs, event.OrderUUID)
+		}
+		byOrder[event.OrderUUID] = append(byOrder[event.OrderUUID], event)
+	}
+
+	halted := make(map[uuid.UUID]bool)
+
+	for wave := 0; ; wave++ {
+		var (
+			waveEvents   []models.OutboxEvent
+			waveMessages []*sarama.ProducerMessage
+		)
+
+		for _, orderUUID := range orderUUIDs {
+			if halted[orderUUID] || len(byOrder[orderUUID]) <= wave {
+				continue
+			}
+
+			event := byOrder[orderUUID][wave]
+
+			msg, err := op.producerMessage(event)
+			if err != nil {
+				op.log.Error("outbox_producer", slog.String("event_uuid", event.EventUUID.String()), slog.String("error", err.Error()))
+				failed[event.EventUUID] = err
+				halted[orderUUID] = true
+				continue
+			}
+
+			waveEvents = append(waveEvents, event)
+			waveMessages = append(waveMessages, msg)
+		}
+
+		if len(waveMessages) == 0 {
+			return published, failed
+		}
+
+		waveFailed, err := sendFailures(op.producer.SendMessages(waveMessages))
+
+		for i, event := range waveEvents {
+			failedErr, ok := waveFailed[waveMessages[i]]
+			if !ok && waveFailed == nil && err != nil {
+				failedErr, ok = err, true
+			}
+
+			if ok {
+				op.log.Error("outbox_producer",
+					slog.String("event_uuid", event.EventUUID.String()),
+					slog.String("order_uuid", event.OrderUUID.String()),
+					slog.String("error", failedErr.Error()),
+				)
+				failed[event.EventUUID] = failedErr
+				halted[event.OrderUUID] = true
+				continue
+			}
+
+			published = append(published, event.EventUUID)
+		}
+	}
+}
+
+// registerFailure учитывает неудачную попытку отправки события: откладывает
+// следующую попытку или, если попытки исчерпаны, переносит событие в outbox_dead_letter
+func (op *OutboxProducer) registerFailure(ctx context.Context, tx *sql.Tx, event models.OutboxEvent, sendErr error) error {
+	attempts := event.Attempts + 1
+
+	if attempts >= op.outboxConfig.MaxAttempts {
+		const deadLetterQuery = `
+									WITH moved AS (
+										DELETE FROM "outbox" WHERE id = $1
+										RETURNING event_uuid, order_uuid, event_type, payload, created_at, aggregate_version
+									)
+									INSERT INTO "outbox_dead_letter"
+										(event_uuid, order_uuid, event_type, payload, created_at, aggregate_version, attempts, last_error)
+										SELECT event_uuid, order_uuid, event_type, payload, created_at, aggregate_version, $2, $3
+											FROM moved
+								`
+
+		if _, err := tx.ExecContext(ctx, deadLetterQuery, event.ID, attempts, sendErr.Error()); err != nil {
+			op.log.Error("outbox_producer", slog.String("error", err.Error()))
+			return fmt.Errorf("move to dead letter: %w", err)
+		}
+
+		op.log.Warn("outbox_producer",
+			slog.String("dead letter", event.EventUUID.String()),
+			slog.String("order_uuid", event.OrderUUID.String()),
+			slog.Int("attempts", attempts),
+		)
+
+		return nil
+	}
+
+	const retryQuery = `
+							UPDATE "outbox"
+								SET attempts = $2, last_error = $3, next_attempt_at = now() + make_interval(secs => $4)
+								WHERE id = $1
+						`
+
+	delay := op.retryDelay(attempts)
+	if _, err := tx.ExecContext(ctx, retryQuery, event.ID, attempts, sendErr.Error(), delay.Seconds()); err != nil {
+		op.log.Error("outbox_producer", slog.String("error", err.Error()))
+		return fmt.Errorf("register failure: %w", err)
+	}
+
+	return nil
+}
+
+// retryDelay - пауза перед попыткой номер attempts+1: RetryBaseDelay * 2^(attempts-1),
+// но не больше RetryMaxDelay
+func (op *OutboxProducer) retryDelay(attempts int) time.Duration {
+	delay := min(op.outboxConfig.RetryBaseDelay, op.outboxConfig.RetryMaxDelay)
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= op.outboxConfig.RetryMaxDelay {
+			return op.outboxConfig.RetryMaxDelay
+		}
+	}
+
+	return delay
+}
+
+func (op *OutboxProducer) producerMessage(event models.OutboxEvent) (*sarama.ProducerMessage, error) {
+	bytes, envelopeHeaders, err := op.encoder.Encode(event)
+	if err != nil {
+		return nil, fmt.Errorf("encode outbox: %w", err)
+	}
+
+	return &sarama.ProducerMessage{
+		Topic:   op.kafkaConfig.OrderEventTopic,
+		Key:     sarama.StringEncoder(event.OrderUUID.String()),
+		Value:   sarama.ByteEncoder(bytes),
+		Headers: append(messageHeaders(event), envelopeHeaders...),
+	}, nil
+}
+
+// sendFailures раскладывает ошибку SendMessages по сообщениям. Если ошибка не
+// sarama.ProducerErrors, неудачными считаются все сообщения.
+func sendFailures(err error) (failed map[*sarama.ProducerMessage]error, all error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var producerErrs sarama.ProducerErrors
+	if !errors.As(err, &producerErrs) {
+		return nil, err
+	}
+
+	failed = make(map[*sarama.ProducerMessage]error, len(producerErrs))
+	for _, producerErr := range producerErrs {
+		failed[producerErr.Msg] = producerErr.Err
+	}
+
+	return failed, err
+}
+
+func messageHeaders(msg models.OutboxEvent) []sarama.RecordHeader {
+	return []sarama.RecordHeader{
+		{Key: []byte(headerEventUUID), Value: []byte(msg.EventUUID.String())},
+		{Key: []byte(headerEventType), Value: []byte(msg.EventType)},
+		{Key: []byte(headerOrderUUID), Value: []byte(msg.OrderUUID.String())},
+		{Key: []byte(headerAggregateVersion), Value: []byte(strconv.Itoa(msg.AggregateVersion))},
+	}
 }
@@ -0,0 +1,163 @@
+package outbox_producer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+// recordingProducer запоминает, сколько раз было опубликовано каждое событие
+type recordingProducer struct {
+	sarama.SyncProducer
+
+	mu   sync.Mutex
+	sent map[string]int
+}
+
+func newRecordingProducer() *recordingProducer {
+	return &recordingProducer{sent: make(map[string]int)}
+}
+
+func (p *recordingProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	// небольшая задержка, чтобы пачки разных релеев гарантированно пересекались во времени
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, msg := range msgs {
+		for _, header := range msg.Headers {
+			if string(header.Key) == headerEventUUID {
+				p.sent[string(header.Value)]++
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *recordingProducer) Close() error {
+	return nil
+}
+
+func TestConcurrentRelaysPublishEachEventOnce(t *testing.T) {
+	db := testdb.New(t)
+	testdb.Truncate(t, db, "outbox")
+
+	const (
+		eventsCount = 500
+		relaysCount = 4
+	)
+
+	eventUUIDs := make([]string, 0, eventsCount)
+	for i := 0; i < eventsCount; i++ {
+		eventUUID := uuid.New()
+		_, err := db.Exec(
+			`INSERT INTO "outbox" (event_uuid, order_uuid, event_type) VALUES ($1, $2, 'OrderCreated')`,
+			eventUUID, uuid.New(),
+		)
+		require.NoError(t, err)
+		eventUUIDs = append(eventUUIDs, eventUUID.String())
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	producer := newRecordingProducer()
+	outboxCfg := config.OutboxConfig{PollInterval: time.Millisecond, BatchSize: 20, MaxIdleBackoff: time.Millisecond, MaxAttempts: 3}
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, relaysCount)
+	for i := 0; i < relaysCount; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			relay := New(producer, db, config.KafkaConfig{OrderEventTopic: "order_topic"}, outboxCfg, newTestEncoder(), log)
+			for {
+				sent, err := relay.ProduceMessages(context.Background())
+				if err != nil {
+					errs <- err
+					return
+				}
+				if sent == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Len(t, producer.sent, eventsCount)
+	for _, eventUUID := range eventUUIDs {
+		require.Equal(t, 1, producer.sent[eventUUID], "event %s", eventUUID)
+	}
+
+	var unsent int
+	require.NoError(t, db.Get(&unsent, `SELECT count(*) FROM "outbox" WHERE send = FALSE`))
+	require.Zero(t, unsent)
+}
+
+func TestDeadLetterBlocksLaterOrderEvents(t *testing.T) {
+	db := testdb.New(t)
+	testdb.Truncate(t, db, "outbox", "outbox_dead_letter")
+
+	orderUUID, otherOrderUUID := uuid.New(), uuid.New()
+	head, later, other := uuid.New(), uuid.New(), uuid.New()
+	for _, event := range []struct{ eventUUID, orderUUID uuid.UUID }{
+		{head, orderUUID},
+		{later, orderUUID},
+		{other, otherOrderUUID},
+	} {
+		_, err := db.Exec(
+			`INSERT INTO "outbox" (event_uuid, order_uuid, event_type) VALUES ($1, $2, 'OrderCreated')`,
+			event.eventUUID, event.orderUUID,
+		)
+		require.NoError(t, err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	producer := &failingProducer{fail: map[string]bool{head.String(): true}}
+	outboxCfg := config.OutboxConfig{PollInterval: time.Millisecond, BatchSize: 10, MaxIdleBackoff: time.Millisecond, MaxAttempts: 1}
+	relay := New(producer, db, config.KafkaConfig{OrderEventTopic: "order_topic"}, outboxCfg, newTestEncoder(), log)
+
+	sent, err := relay.ProduceMessages(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+
+	var deadLetters []uuid.UUID
+	require.NoError(t, db.Select(&deadLetters, `SELECT event_uuid FROM "outbox_dead_letter"`))
+	require.Equal(t, []uuid.UUID{head}, deadLetters)
+
+	// брокер снова доступен, но следующее событие заказа не должно обогнать
+	// событие из outbox_dead_letter
+	producer.fail = nil
+	sent, err = relay.ProduceMessages(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, sent)
+
+	var send bool
+	require.NoError(t, db.Get(&send, `SELECT send FROM "outbox" WHERE event_uuid = $1`, later))
+	require.False(t, send)
+
+	// после разбора события заказа снова публикуются
+	_, err = db.Exec(`DELETE FROM "outbox_dead_letter" WHERE order_uuid = $1`, orderUUID)
+	require.NoError(t, err)
+
+	sent, err = relay.ProduceMessages(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+	require.Equal(t, later.String(), headerValue(producer.waves[len(producer.waves)-1][0], headerEventUUID))
+}
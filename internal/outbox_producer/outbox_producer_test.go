@@ -0,0 +1,174 @@
+package outbox_producer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding"
+)
+
+// failingProducer отклоняет сообщения с event_uuid из fail и запоминает порядок отправки
+type failingProducer struct {
+	sarama.SyncProducer
+
+	fail    map[string]bool
+	failAll error
+	waves   [][]*sarama.ProducerMessage
+}
+
+func (p *failingProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	p.waves = append(p.waves, msgs)
+
+	if p.failAll != nil {
+		return p.failAll
+	}
+
+	var errs sarama.ProducerErrors
+	for _, msg := range msgs {
+		if p.fail[headerValue(msg, headerEventUUID)] {
+			errs = append(errs, &sarama.ProducerError{Msg: msg, Err: sarama.ErrOutOfBrokers})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func headerValue(msg *sarama.ProducerMessage, key string) string {
+	for _, header := range msg.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+
+	return ""
+}
+
+func newTestEncoder() messageEncoder {
+	encoder, err := encoding.NewCloudEvents(encoding.NewJSONEncoder(), "/order_service", encoding.ModeBinary)
+	if err != nil {
+		panic(err)
+	}
+
+	return encoder
+}
+
+func newTestProducer(producer sarama.SyncProducer) *OutboxProducer {
+	return New(
+		producer,
+		nil,
+		config.KafkaConfig{OrderEventTopic: "order_topic"},
+		config.OutboxConfig{BatchSize: 100},
+		newTestEncoder(),
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+}
+
+func newTestEvents(orderUUID uuid.UUID, count int) []models.OutboxEvent {
+	events := make([]models.OutboxEvent, 0, count)
+	for i := 0; i < count; i++ {
+		events = append(events, models.OutboxEvent{
+			EventUUID: uuid.New(),
+			OrderUUID: orderUUID,
+			EventType: models.EventTypeOrderCreated,
+			Payload:   []byte(`{}`),
+		})
+	}
+
+	return events
+}
+
+func TestPublishKeepsOrderPerOrder(t *testing.T) {
+	orderA, orderB := uuid.New(), uuid.New()
+	eventsA := newTestEvents(orderA, 3)
+	eventsB := newTestEvents(orderB, 2)
+
+	producer := &failingProducer{fail: map[string]bool{eventsA[1].EventUUID.String(): true}}
+	op := newTestProducer(producer)
+
+	// события разных заказов перемешаны так же, как они лежат в outbox по id
+	events := []models.OutboxEvent{eventsA[0], eventsB[0], eventsA[1], eventsB[1], eventsA[2]}
+
+	published, failed := op.publish(events)
+	require.Equal(t, []uuid.UUID{eventsA[0].EventUUID, eventsB[0].EventUUID, eventsB[1].EventUUID}, published)
+	require.Len(t, failed, 1)
+	require.ErrorIs(t, failed[eventsA[1].EventUUID], sarama.ErrOutOfBrokers)
+
+	// третье событие заказа A не отправлялось вовсе, раз второе не дошло
+	require.Len(t, producer.waves, 2)
+	for _, wave := range producer.waves {
+		for _, msg := range wave {
+			require.NotEqual(t, eventsA[2].EventUUID.String(), headerValue(msg, headerEventUUID))
+
+			key, keyErr := msg.Key.Encode()
+			require.NoError(t, keyErr)
+			require.Equal(t, headerValue(msg, headerOrderUUID), string(key))
+		}
+	}
+}
+
+func TestPublishGenericErrorHaltsWave(t *testing.T) {
+	producer := &failingProducer{failAll: errors.New("broker is down")}
+	op := newTestProducer(producer)
+
+	events := append(newTestEvents(uuid.New(), 2), newTestEvents(uuid.New(), 1)...)
+
+	published, failed := op.publish(events)
+	require.Empty(t, published)
+	require.Len(t, failed, 2)
+	require.Contains(t, failed, events[0].EventUUID)
+	require.Contains(t, failed, events[2].EventUUID)
+	require.Len(t, producer.waves, 1)
+}
+
+func TestRetryDelay(t *testing.T) {
+	op := &OutboxProducer{
+		outboxConfig: config.OutboxConfig{
+			RetryBaseDelay: time.Second,
+			RetryMaxDelay:  10 * time.Second,
+		},
+	}
+
+	tCases := []struct {
+		attempts int
+		exp      time.Duration
+	}{
+		{attempts: 1, exp: time.Second},
+		{attempts: 2, exp: 2 * time.Second},
+		{attempts: 4, exp: 8 * time.Second},
+		{attempts: 5, exp: 10 * time.Second},
+		{attempts: 50, exp: 10 * time.Second},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(fmt.Sprintf("attempt_%d", tCase.attempts), func(t *testing.T) {
+			require.Equal(t, tCase.exp, op.retryDelay(tCase.attempts))
+		})
+	}
+}
+
+func TestProducerMessageHeaders(t *testing.T) {
+	op := newTestProducer(&failingProducer{})
+	event := newTestEvents(uuid.New(), 1)[0]
+
+	msg, err := op.producerMessage(event)
+	require.NoError(t, err)
+
+	require.Equal(t, event.EventUUID.String(), headerValue(msg, headerEventUUID))
+	require.Equal(t, event.EventUUID.String(), headerValue(msg, "ce_id"))
+	require.Equal(t, "order.created", headerValue(msg, "ce_type"))
+	require.Equal(t, event.OrderUUID.String(), headerValue(msg, "ce_subject"))
+	require.Equal(t, encoding.ContentTypeJSON, headerValue(msg, encoding.HeaderContentType))
+	require.Equal(t, "5", headerValue(msg, encoding.HeaderSchemaVersion))
+}
@@ -0,0 +1,79 @@
+package outbox_producer
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Run публикует события из outbox, пока не будет отменён ctx.
+//
+// Пока в таблице есть бэклог (пачка пришла полной), пачки отправляются друг за
+// другом без паузы. Если таблица пуста, интервал опроса удваивается вплоть до
+// MaxIdleBackoff, а как только появляются события - сбрасывается к PollInterval.
+//
+// Сигнал из wakeups прерывает ожидание и запускает публикацию сразу, опрос по
+// таймеру при этом остаётся страховкой от потерянных уведомлений. Если wakeups
+// равен nil, релей работает только на опросе.
+//
+// Отмена ctx не прерывает уже начатую пачку: она публикуется и коммитится
+// до конца, после чего Run возвращает управление.
+func (op *OutboxProducer) Run(ctx context.Context, wakeups <-chan struct{}) error {
+	const logOp = "outbox_producer.Run"
+
+	// пачка в полёте не должна откатываться из-за сигнала остановки
+	batchCtx := context.WithoutCancel(ctx)
+
+	wait := op.outboxConfig.PollInterval
+
+	for {
+		sent, err := op.ProduceMessages(batchCtx)
+		switch {
+		case err != nil:
+			op.log.Error(logOp, slog.String("produce messages error", err.Error()))
+			wait = op.nextIdleWait(wait)
+		case sent >= op.outboxConfig.BatchSize:
+			op.log.Debug(logOp, slog.Int("sent", sent), slog.String("backlog", "draining"))
+			wait = 0
+		case sent > 0:
+			op.log.Debug(logOp, slog.Int("sent", sent))
+			wait = op.outboxConfig.PollInterval
+		default:
+			wait = op.nextIdleWait(wait)
+		}
+
+		if ctx.Err() != nil {
+			op.log.Info(logOp, slog.String("status", "stopped"))
+			return nil
+		}
+
+		if wait == 0 {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			op.log.Info(logOp, slog.String("status", "stopped"))
+			return nil
+		case <-wakeups:
+			timer.Stop()
+			wait = op.outboxConfig.PollInterval
+		case <-timer.C:
+		}
+	}
+}
+
+func (op *OutboxProducer) nextIdleWait(current time.Duration) time.Duration {
+	if current < op.outboxConfig.PollInterval {
+		return op.outboxConfig.PollInterval
+	}
+
+	next := current * 2
+	if next > op.outboxConfig.MaxIdleBackoff {
+		return op.outboxConfig.MaxIdleBackoff
+	}
+
+	return next
+}
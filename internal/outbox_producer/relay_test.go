@@ -0,0 +1,36 @@
+package outbox_producer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+)
+
+func TestNextIdleWait(t *testing.T) {
+	op := &OutboxProducer{
+		outboxConfig: config.OutboxConfig{
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			MaxIdleBackoff: 5 * time.Second,
+		},
+	}
+
+	tCases := []struct {
+		name    string
+		current time.Duration
+		exp     time.Duration
+	}{
+		{name: "after_drain", current: 0, exp: time.Second},
+		{name: "double", current: time.Second, exp: 2 * time.Second},
+		{name: "capped", current: 4 * time.Second, exp: 5 * time.Second},
+		{name: "stays_at_max", current: 5 * time.Second, exp: 5 * time.Second},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			require.Equal(t, tCase.exp, op.nextIdleWait(tCase.current))
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package outbox_producer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+)
+
+// Retention удаляет из outbox отправленные события старше cfg.Age, либо
+// переносит их в outbox_archive, если включён cfg.Archive
+type Retention struct {
+	db  *sqlx.DB
+	cfg config.OutboxRetentionConfig
+	log *slog.Logger
+}
+
+func NewRetention(db *sqlx.DB, cfg config.OutboxRetentionConfig, log *slog.Logger) *Retention {
+	return &Retention{
+		db:  db,
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// Run запускает очистку сразу и затем каждые cfg.Interval, пока не будет отменён ctx
+func (r *Retention) Run(ctx context.Context) error {
+	const op = "outbox_producer.Retention.Run"
+
+	if r.cfg.Age <= 0 {
+		r.log.Info(op, slog.String("status", "disabled"))
+		return nil
+	}
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := r.Cleanup(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error(op, slog.String("cleanup error", err.Error()))
+		}
+		if removed > 0 {
+			r.log.Info(op, slog.Int64("removed", removed), slog.Bool("archived", r.cfg.Archive))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cleanup удаляет устаревшие события пачками по cfg.BatchSize, каждая пачка - отдельная
+// короткая транзакция. Возвращает общее количество удалённых строк.
+func (r *Retention) Cleanup(ctx context.Context) (removed int64, err error) {
+	const op = "outbox_producer.Retention.Cleanup"
+
+	for {
+		var batchRemoved int64
+		batchRemoved, err = r.cleanupBatch(ctx)
+		removed += batchRemoved
+		if err != nil {
+			return removed, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if batchRemoved < int64(r.cfg.BatchSize) {
+			return removed, nil
+		}
+	}
+}
+
+func (r *Retention) cleanupBatch(ctx context.Context) (int64, error) {
+	const deleteQuery = `
+							DELETE FROM "outbox"
+								WHERE id IN (
+									SELECT id FROM "outbox"
+										WHERE send = TRUE AND sent_at < now() - make_interval(secs => $1)
+										ORDER BY id
+										LIMIT $2
+										FOR UPDATE SKIP LOCKED
+								)
+						`
+
+	const archiveQuery = `
+							WITH expired AS (
+								DELETE FROM "outbox"
+									WHERE id IN (
+										SELECT id FROM "outbox"
+											WHERE send = TRUE AND sent_at < now() - make_interval(secs => $1)
+											ORDER BY id
+											LIMIT $2
+											FOR UPDATE SKIP LOCKED
+									)
+									RETURNING id, event_uuid, order_uuid, event_type, payload, created_at,
+										aggregate_version, attempts, sent_at
+							)
+							INSERT INTO "outbox_archive"
+								(id, event_uuid, order_uuid, event_type, payload, created_at, aggregate_version, attempts, sent_at)
+								SELECT id, event_uuid, order_uuid, event_type, payload, created_at, aggregate_version, attempts, sent_at
+									FROM expired
+								ON CONFLICT (id) DO NOTHING
+						`
+
+	query := deleteQuery
+	if r.cfg.Archive {
+		query = archiveQuery
+	}
+
+	res, err := r.db.ExecContext(ctx, query, r.cfg.Age.Seconds(), r.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("execute statement: %w", err)
+	}
+
+	return res.RowsAffected()
+}
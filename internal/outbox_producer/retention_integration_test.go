@@ -0,0 +1,72 @@
+package outbox_producer
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/config"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func insertOutboxRow(t *testing.T, db *sqlx.DB, send bool, sentAgo time.Duration) {
+	t.Helper()
+
+	_, err := db.Exec(`
+		INSERT INTO "outbox" (event_uuid, order_uuid, event_type, send, sent_at)
+			VALUES ($1, $2, 'OrderCreated', $3, CASE WHEN $3 THEN now() - make_interval(secs => $4) END)`,
+		uuid.New(), uuid.New(), send, sentAgo.Seconds(),
+	)
+	require.NoError(t, err)
+}
+
+func TestRetentionCleanup(t *testing.T) {
+	db := testdb.New(t)
+
+	tCases := []struct {
+		name    string
+		archive bool
+	}{
+		{name: "delete", archive: false},
+		{name: "archive", archive: true},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			testdb.Truncate(t, db, "outbox", "outbox_archive")
+
+			for i := 0; i < 5; i++ {
+				insertOutboxRow(t, db, true, 48*time.Hour)
+			}
+			insertOutboxRow(t, db, true, time.Minute)
+			insertOutboxRow(t, db, false, 0)
+
+			retention := NewRetention(db, config.OutboxRetentionConfig{
+				Age:       24 * time.Hour,
+				BatchSize: 2,
+				Archive:   tCase.archive,
+			}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			removed, err := retention.Cleanup(context.Background())
+			require.NoError(t, err)
+			require.EqualValues(t, 5, removed)
+
+			var left int
+			require.NoError(t, db.Get(&left, `SELECT count(*) FROM "outbox"`))
+			require.Equal(t, 2, left)
+
+			var archived int
+			require.NoError(t, db.Get(&archived, `SELECT count(*) FROM "outbox_archive"`))
+			if tCase.archive {
+				require.Equal(t, 5, archived)
+			} else {
+				require.Zero(t, archived)
+			}
+		})
+	}
+}
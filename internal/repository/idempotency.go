@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+type IdempotencyRepository struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewIdempotencyRepository(log *slog.Logger, db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Reserve занимает ключ идемпотентности под запрос owner с хэшем requestHash.
+//
+// Если ключ свободен, истёк или занят запросом, который не завершился за
+// lockTimeout, ключ занимается и возвращается nil, nil - запрос нужно выполнить
+// и сохранить ответ через Complete с тем же owner. Если по ключу уже сохранён ответ на тот же
+// запрос, он возвращается. Ключ, занятый другим запросом, даёт
+// ErrIdempotencyKeyReused, а ещё не завершённым тем же запросом -
+// ErrIdempotencyKeyInProgress.
+func (ir *IdempotencyRepository) Reserve(
+	ctx context.Context,
+	key string,
+	owner uuid.UUID,
+	requestHash string,
+	ttl time.Duration,
+	lockTimeout time.Duration,
+) (*models.IdempotentResponse, error) {
+	const op = "repository.idempotency.Reserve"
+
+	// конкурентная вставка того же ключа ждёт на уникальном индексе, после чего
+	// условие WHERE решает, можно ли занять существующую строку
+	const reserveQuery = `
+							INSERT INTO "idempotency_key" (key, request_hash, expires_at, locked_by)
+								VALUES ($1, $2, now() + make_interval(secs => $3), $5)
+								ON CONFLICT (key) DO UPDATE
+									SET request_hash = EXCLUDED.request_hash,
+										locked_by    = EXCLUDED.locked_by,
+										status_code  = NULL,
+										response     = NULL,
+										created_at   = now(),
+										expires_at   = EXCLUDED.expires_at
+									WHERE idempotency_key.expires_at < now()
+										OR (idempotency_key.status_code IS NULL
+											AND idempotency_key.created_at < now() - make_interval(secs => $4))
+								RETURNING key
+						`
+
+	var reserved string
+	err := ir.db.QueryRowContext(ctx, reserveQuery, key, requestHash, ttl.Seconds(), lockTimeout.Seconds(), owner).
+		Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: reserve key: %w", op, err)
+	}
+
+	const selectQuery = `SELECT request_hash, status_code, response FROM "idempotency_key" WHERE key = $1`
+
+	var (
+		storedHash string
+		statusCode sql.NullInt64
+		response   []byte
+	)
+	if err = ir.db.QueryRowContext(ctx, selectQuery, key).Scan(&storedHash, &statusCode, &response); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// первый запрос освободил ключ между вставкой и чтением
+			return nil, fmt.Errorf("%s: %w", op, internal_errors.ErrIdempotencyKeyInProgress)
+		}
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: select key: %w", op, err)
+	}
+
+	if storedHash != requestHash {
+		return nil, fmt.Errorf("%s: %w", op, internal_errors.ErrIdempotencyKeyReused)
+	}
+
+	if !statusCode.Valid {
+		return nil, fmt.Errorf("%s: %w", op, internal_errors.ErrIdempotencyKeyInProgress)
+	}
+
+	return &models.IdempotentResponse{
+		StatusCode: int(statusCode.Int64),
+		Body:       response,
+	}, nil
+}
+
+// Complete сохраняет ответ на запрос owner, занявший ключ. Если ключ за
+// это время перехватил другой запрос, ответ не сохраняется и возвращается
+// ErrIdempotencyKeyLockLost.
+func (ir *IdempotencyRepository) Complete(
+	ctx context.Context,
+	key string,
+	owner uuid.UUID,
+	response models.IdempotentResponse,
+) error {
+	const op = "repository.idempotency.Complete"
+
+	const query = `
+					UPDATE "idempotency_key" SET status_code = $3, response = $4
+						WHERE key = $1 AND locked_by = $2 AND status_code IS NULL
+				`
+
+	result, err := ir.db.ExecContext(ctx, query, key, owner, response.StatusCode, response.Body)
+	if err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	completed, err := result.RowsAffected()
+	if err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if completed == 0 {
+		return fmt.Errorf("%s: %w", op, internal_errors.ErrIdempotencyKeyLockLost)
+	}
+
+	return nil
+}
+
+// Release освобождает ключ, если запрос owner завершился ошибкой и его можно
+// повторить. Ключ, перехваченный другим запросом, не трогается.
+func (ir *IdempotencyRepository) Release(ctx context.Context, key string, owner uuid.UUID) error {
+	const op = "repository.idempotency.Release"
+
+	const query = `DELETE FROM "idempotency_key" WHERE key = $1 AND locked_by = $2 AND status_code IS NULL`
+
+	if _, err := ir.db.ExecContext(ctx, query, key, owner); err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeExpired удаляет ключи с истёкшим expires_at пачками по batchSize, каждая
+// пачка - отдельный короткий запрос. Reserve занимает истёкшие ключи заново, но
+// без очистки строки остаются в таблице навсегда. Возвращает количество
+// удалённых ключей.
+func (ir *IdempotencyRepository) PurgeExpired(ctx context.Context, batchSize int) (int64, error) {
+	const op = "repository.idempotency.PurgeExpired"
+
+	const query = `
+					DELETE FROM "idempotency_key"
+						WHERE key IN (
+							SELECT key FROM "idempotency_key"
+								WHERE expires_at < now()
+								ORDER BY expires_at
+								LIMIT $1
+								FOR UPDATE SKIP LOCKED
+						)
+				`
+
+	var purged int64
+	for {
+		result, err := ir.db.ExecContext(ctx, query, batchSize)
+		if err != nil {
+			ir.log.Error(op, slog.String("error", err.Error()))
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+
+		batchPurged, err := result.RowsAffected()
+		if err != nil {
+			ir.log.Error(op, slog.String("error", err.Error()))
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+		purged += batchPurged
+
+		if batchPurged < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
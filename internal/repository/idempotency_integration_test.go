@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func newIdempotencyTest(t *testing.T) *IdempotencyRepository {
+	t.Helper()
+
+	db := testdb.New(t)
+	testdb.Truncate(t, db, "idempotency_key")
+
+	return NewIdempotencyRepository(slog.New(slog.NewTextHandler(io.Discard, nil)), db)
+}
+
+func TestIdempotencyReserveAndReplay(t *testing.T) {
+	repo := newIdempotencyTest(t)
+	ctx := context.Background()
+	owner := uuid.New()
+
+	stored, err := repo.Reserve(ctx, "key", owner, "hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, stored)
+
+	_, err = repo.Reserve(ctx, "key", owner, "hash", time.Hour, time.Minute)
+	require.ErrorIs(t, err, internal_errors.ErrIdempotencyKeyInProgress)
+
+	response := models.IdempotentResponse{StatusCode: 200, Body: []byte(`{"order_uuid":"1"}`)}
+	require.NoError(t, repo.Complete(ctx, "key", owner, response))
+
+	stored, err = repo.Reserve(ctx, "key", owner, "hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, &response, stored)
+
+	_, err = repo.Reserve(ctx, "key", uuid.New(), "other hash", time.Hour, time.Minute)
+	require.ErrorIs(t, err, internal_errors.ErrIdempotencyKeyReused)
+}
+
+func TestIdempotencyReleaseAndTakeover(t *testing.T) {
+	repo := newIdempotencyTest(t)
+	ctx := context.Background()
+	owner := uuid.New()
+
+	_, err := repo.Reserve(ctx, "released", owner, "hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.Release(ctx, "released", owner))
+
+	stored, err := repo.Reserve(ctx, "released", uuid.New(), "other hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, stored)
+
+	// брошенный незавершённый запрос занимается снова после lockTimeout
+	_, err = repo.Reserve(ctx, "abandoned", owner, "hash", time.Hour, 0)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	takeover := uuid.New()
+	stored, err = repo.Reserve(ctx, "abandoned", takeover, "hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, stored)
+
+	// брошенный запрос не может ни сохранить ответ, ни освободить чужой ключ
+	err = repo.Complete(ctx, "abandoned", owner, models.IdempotentResponse{StatusCode: 200})
+	require.ErrorIs(t, err, internal_errors.ErrIdempotencyKeyLockLost)
+	require.NoError(t, repo.Release(ctx, "abandoned", owner))
+
+	_, err = repo.Reserve(ctx, "abandoned", uuid.New(), "hash", time.Hour, time.Minute)
+	require.ErrorIs(t, err, internal_errors.ErrIdempotencyKeyInProgress)
+
+	response := models.IdempotentResponse{StatusCode: 200, Body: []byte(`{"order_uuid":"2"}`)}
+	require.NoError(t, repo.Complete(ctx, "abandoned", takeover, response))
+	stored, err = repo.Reserve(ctx, "abandoned", uuid.New(), "hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, &response, stored)
+
+	// истёкший ответ больше не отдаётся, ключ занимается новым запросом
+	_, err = repo.Reserve(ctx, "expired", owner, "hash", 0, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.Complete(ctx, "expired", owner, models.IdempotentResponse{StatusCode: 200}))
+	time.Sleep(10 * time.Millisecond)
+
+	stored, err = repo.Reserve(ctx, "expired", uuid.New(), "other hash", time.Hour, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, stored)
+}
+
+func TestIdempotencyConcurrentReserve(t *testing.T) {
+	repo := newIdempotencyTest(t)
+
+	const requests = 8
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.Reserve(context.Background(), "key", uuid.New(), "hash", time.Hour, time.Minute)
+			if err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+				return
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.ErrorIs(t, err, internal_errors.ErrIdempotencyKeyInProgress)
+	}
+	require.Equal(t, 1, reserved)
+}
+
+func TestIdempotencyPurgeExpired(t *testing.T) {
+	repo := newIdempotencyTest(t)
+	ctx := context.Background()
+
+	insertKey := func(key string, expiresIn time.Duration) {
+		t.Helper()
+
+		_, err := repo.db.Exec(`
+			INSERT INTO "idempotency_key" (key, request_hash, status_code, expires_at)
+				VALUES ($1, 'hash', 200, now() + make_interval(secs => $2))`,
+			key, expiresIn.Seconds(),
+		)
+		require.NoError(t, err)
+	}
+
+	for _, key := range []string{"expired-1", "expired-2", "expired-3"} {
+		insertKey(key, -time.Hour)
+	}
+	insertKey("live", time.Hour)
+
+	purged, err := repo.PurgeExpired(ctx, 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, purged)
+
+	var left []string
+	require.NoError(t, repo.db.Select(&left, `SELECT key FROM "idempotency_key"`))
+	require.Equal(t, []string{"live"}, left)
+}
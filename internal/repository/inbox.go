@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type InboxRepository struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewInboxRepository(log *slog.Logger, db *sqlx.DB) *InboxRepository {
+	return &InboxRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// ProcessOnce выполняет handler для события eventUUID, если consumer ещё не
+// обрабатывал его, и возвращает processed = false для повторной доставки.
+// Все изменения handler должен делать через tx, тогда они закоммитятся вместе
+// с отметкой об обработке события.
+//
+// Отметка в inbox вставляется первой в той же транзакции, что и handler.
+// Конкурентная доставка того же события блокируется на уникальном ключе до
+// конца первой транзакции: после коммита она пропускает событие, после отката
+// обрабатывает его сама. Если handler вернул ошибку, транзакция откатывается
+// вместе с отметкой, и событие можно обработать повторно.
+func (ir *InboxRepository) ProcessOnce(
+	ctx context.Context,
+	consumer string,
+	eventUUID uuid.UUID,
+	handler func(ctx context.Context, tx *sql.Tx) error,
+) (processed bool, err error) {
+	const op = "repository.inbox.ProcessOnce"
+
+	tx, err := ir.db.BeginTx(ctx, nil)
+	if err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil || !processed {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+				err = errors.Join(err, rollbackErr)
+			}
+		}
+	}()
+
+	const inboxQuery = `
+							INSERT INTO "inbox" (consumer, event_uuid) VALUES ($1, $2)
+								ON CONFLICT DO NOTHING
+						`
+
+	res, err := tx.ExecContext(ctx, inboxQuery, consumer, eventUUID)
+	if err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: inbox insert: %w", op, err)
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: rows affected: %w", op, err)
+	}
+	if inserted == 0 {
+		ir.log.Debug(op,
+			slog.String("consumer", consumer),
+			slog.String("event_uuid", eventUUID.String()),
+			slog.String("skip", "already processed"),
+		)
+		return false, nil
+	}
+
+	if err = handler(ctx, tx); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		ir.log.Error(op, slog.String("error", err.Error()))
+		return false, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+const testConsumer = "inbox_test"
+
+// newInboxTest готовит репозиторий и таблицу, в которую обработчик пишет побочный эффект
+func newInboxTest(t *testing.T) (*InboxRepository, *sqlx.DB) {
+	t.Helper()
+
+	db := testdb.New(t)
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS "inbox_test_effect" (event_uuid uuid NOT NULL)`)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = db.Exec(`DROP TABLE IF EXISTS "inbox_test_effect"`)
+	})
+
+	testdb.Truncate(t, db, "inbox", "inbox_test_effect")
+
+	return NewInboxRepository(slog.New(slog.NewTextHandler(io.Discard, nil)), db), db
+}
+
+func effectHandler(eventUUID uuid.UUID) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO "inbox_test_effect" (event_uuid) VALUES ($1)`, eventUUID)
+		return err
+	}
+}
+
+func effectsCount(t *testing.T, db *sqlx.DB, eventUUID uuid.UUID) int {
+	t.Helper()
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT count(*) FROM "inbox_test_effect" WHERE event_uuid = $1`, eventUUID))
+
+	return count
+}
+
+func TestInboxSkipsRedelivery(t *testing.T) {
+	repo, db := newInboxTest(t)
+	ctx := context.Background()
+	eventUUID := uuid.New()
+
+	processed, err := repo.ProcessOnce(ctx, testConsumer, eventUUID, effectHandler(eventUUID))
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	processed, err = repo.ProcessOnce(ctx, testConsumer, eventUUID, effectHandler(eventUUID))
+	require.NoError(t, err)
+	require.False(t, processed)
+
+	require.Equal(t, 1, effectsCount(t, db, eventUUID))
+
+	// другой консьюмер ведёт свой учёт и обрабатывает то же событие
+	processed, err = repo.ProcessOnce(ctx, "other_consumer", eventUUID, effectHandler(eventUUID))
+	require.NoError(t, err)
+	require.True(t, processed)
+}
+
+func TestInboxHandlerErrorAllowsRetry(t *testing.T) {
+	repo, db := newInboxTest(t)
+	ctx := context.Background()
+	eventUUID := uuid.New()
+	handlerErr := errors.New("handler failed")
+
+	processed, err := repo.ProcessOnce(ctx, testConsumer, eventUUID, func(ctx context.Context, tx *sql.Tx) error {
+		if err := effectHandler(eventUUID)(ctx, tx); err != nil {
+			return err
+		}
+		return handlerErr
+	})
+	require.ErrorIs(t, err, handlerErr)
+	require.False(t, processed)
+	require.Zero(t, effectsCount(t, db, eventUUID))
+
+	processed, err = repo.ProcessOnce(ctx, testConsumer, eventUUID, effectHandler(eventUUID))
+	require.NoError(t, err)
+	require.True(t, processed)
+	require.Equal(t, 1, effectsCount(t, db, eventUUID))
+}
+
+func TestInboxConcurrentDuplicatesProcessedOnce(t *testing.T) {
+	repo, db := newInboxTest(t)
+	eventUUID := uuid.New()
+
+	const deliveries = 8
+
+	handler := func(ctx context.Context, tx *sql.Tx) error {
+		// держим транзакцию открытой, чтобы дубли гарантированно пересеклись
+		time.Sleep(50 * time.Millisecond)
+		return effectHandler(eventUUID)(ctx, tx)
+	}
+
+	var (
+		wg             sync.WaitGroup
+		mu             sync.Mutex
+		processedCount int
+	)
+	errs := make(chan error, deliveries)
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			processed, err := repo.ProcessOnce(context.Background(), testConsumer, eventUUID, handler)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if processed {
+				mu.Lock()
+				processedCount++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 1, processedCount)
+	require.Equal(t, 1, effectsCount(t, db, eventUUID))
+}
+
+func TestInboxMarkPaidRedelivery(t *testing.T) {
+	orders, db := newOrderListTest(t)
+	testdb.Truncate(t, db, "inbox")
+
+	inbox := NewInboxRepository(slog.New(slog.NewTextHandler(io.Discard, nil)), db)
+	ctx := context.Background()
+
+	orderUUID := createOrderAt(t, orders, db, uuid.New(), models.Card, time.Now())
+	eventUUID := uuid.New()
+	markPaid := func(ctx context.Context, tx *sql.Tx) error {
+		return orders.MarkPaidTx(ctx, tx, orderUUID, models.StatusChange{Actor: models.ActorPayment})
+	}
+
+	processed, err := inbox.ProcessOnce(ctx, testConsumer, eventUUID, markPaid)
+	require.NoError(t, err)
+	require.True(t, processed)
+
+	// повторная доставка не доходит до перехода и не пишет второе событие
+	processed, err = inbox.ProcessOnce(ctx, testConsumer, eventUUID, markPaid)
+	require.NoError(t, err)
+	require.False(t, processed)
+
+	history, err := orders.StatusHistory(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, models.OrderStatusPaid, history[1].ToStatus)
+
+	var events int
+	require.NoError(t, db.Get(&events, `SELECT count(*) FROM "outbox" WHERE order_uuid = $1`, orderUUID))
+	require.Equal(t, 2, events)
+}
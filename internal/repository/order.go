@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
@@ -10,10 +11,15 @@ import (
 	"github.com/lib/pq"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/outbox"
 	"log/slog"
 	"strings"
 )
 
+// initialOrderVersion - версия агрегата заказа сразу после создания,
+// каждое изменение статуса увеличивает её на единицу
+const initialOrderVersion = 1
+
 type OrderRepository struct {
 	log *slog.Logger
 	db  *sqlx.DB
@@ -26,12 +32,38 @@ func NewOrderRepository(log *slog.Logger, db *sqlx.DB) *OrderRepository {
 	}
 }
 
-func (or *OrderRepository) Create(
+// createAttempts - сколько раз Create выполняет транзакцию при конфликте
+// сериализации: конкурентные заказы одного пользователя удерживают баллы с
+// одной строки points_account и расходуют промокод с одной строки promo_code_usage
+const createAttempts = 3
+
+// serializationFailure - код ошибки Postgres при конфликте сериализуемых транзакций
+const serializationFailure = "40001"
+
+func (or *OrderRepository) Create(ctx context.Context, order *models.Order) (uuid.UUID, error) {
+	const op = "repository.order.Create"
+
+	for attempt := 1; ; attempt++ {
+		orderUUID, err := or.create(ctx, op, order)
+		if err == nil {
+			order.Version = initialOrderVersion
+			return orderUUID, nil
+		}
+
+		var pqErr *pq.Error
+		if attempt == createAttempts || !errors.As(err, &pqErr) || pqErr.Code != serializationFailure {
+			return uuid.Nil, err
+		}
+
+		or.log.Warn(op, slog.Int("attempt", attempt), slog.String("retry after", err.Error()))
+	}
+}
+
+func (or *OrderRepository) create(
 	ctx context.Context,
+	op string,
 	order *models.Order,
 ) (orderUUID uuid.UUID, err error) {
-	const op = "repository.order.Create"
-
 	tx, err := or.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 	if err != nil {
 		or.log.Error(op, slog.String("error", err.Error()))
@@ -47,9 +79,20 @@ func (or *OrderRepository) Create(
 		}
 	}()
 
-	const orderQuery = `INSERT INTO "order" (user_uuid, status, payment_type) VALUES ($1, $2, $3) RETURNING uuid`
+	const orderQuery = `
+							INSERT INTO "order" (
+									user_uuid, status, payment_type, currency, total_amount,
+									promo_code, promo_discount, points_discount, grand_total, with_points
+								)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+								RETURNING uuid
+						`
 
-	row := tx.QueryRowContext(ctx, orderQuery, order.UserUUID, order.Status, order.PaymentType)
+	row := tx.QueryRowContext(ctx, orderQuery,
+		order.UserUUID, order.Status, order.PaymentType, order.Currency, order.TotalAmount,
+		sql.NullString{String: order.PromoCode, Valid: order.PromoCode != ""}, order.PromoDiscount,
+		order.PointsDiscount, order.GrandTotal, order.WithPoints,
+	)
 	if err != nil {
 		or.log.Error(op, slog.String("error", err.Error()))
 		return uuid.Nil, fmt.Errorf("%s: order execute statement: %w", op, err)
@@ -60,16 +103,48 @@ func (or *OrderRepository) Create(
 		return uuid.Nil, fmt.Errorf("%s: scan result: %w", op, err)
 	}
 
-	const orderProductsQuery = `INSERT INTO "order_products" (order_uuid, product_uuid, amount) VALUES %s`
+	// использование промокода учитывается в той же транзакции: если лимит
+	// исчерпан или код перестал действовать, заказ не создаётся
+	if order.PromoCode != "" {
+		if err = consumePromo(ctx, tx, order.PromoCode, order.UserUUID); err != nil {
+			if !errors.Is(err, internal_errors.ErrPromoCodeInactive) &&
+				!errors.Is(err, internal_errors.ErrPromoCodeLimitReached) {
+				or.log.Error(op, slog.String("promo consume error", err.Error()))
+			}
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err = insertPromoDiscount(ctx, tx, orderUUID, order.PromoCode, order.PromoDiscount); err != nil {
+			or.log.Error(op, slog.String("error", err.Error()))
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	// баллы удерживаются в той же транзакции, что и создание заказа:
+	// если их не хватает, заказ не создаётся
+	if order.PointsDiscount > 0 {
+		if err = holdPoints(ctx, tx, order.UserUUID, orderUUID, order.PointsDiscount); err != nil {
+			if !errors.Is(err, internal_errors.ErrInsufficientPoints) {
+				or.log.Error(op, slog.String("points hold error", err.Error()))
+			}
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	const orderProductsQuery = `
+								INSERT INTO "order_products" (order_uuid, product_uuid, quantity, unit_price, currency)
+									VALUES %s
+								`
 	var values []interface{}
 	var placeholders []string
 
 	for i, product := range order.Products {
-		values = append(values, orderUUID, product.UUID, product.Amount)
+		values = append(values, orderUUID, product.UUID, product.Quantity, product.UnitPrice, product.Currency)
 
-		argId := i * 3
+		argId := i * 5
 
-		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d)", argId+1, argId+2, argId+3))
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)",
+			argId+1, argId+2, argId+3, argId+4, argId+5))
 	}
 
 	fullQuery := fmt.Sprintf(orderProductsQuery, strings.Join(placeholders, ","))
@@ -79,17 +154,23 @@ func (or *OrderRepository) Create(
 		return uuid.Nil, fmt.Errorf("%s: order_products execute statement: %w", op, err)
 	}
 
-	eventUUID, err := uuid.NewUUID()
-	if err != nil {
-		or.log.Error(op, slog.String("error", err.Error()))
-		return uuid.Nil, fmt.Errorf("%s: event_uuid generate error: %w", op, err)
+	creation := models.StatusChange{Actor: order.UserUUID.String(), Reason: "order created"}
+	if err = or.insertStatusHistory(ctx, tx, orderUUID, models.UndefinedStatus, order.Status, creation); err != nil {
+		or.log.Error(op, slog.String("status history insert error", err.Error()))
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	const outboxQuery = `INSERT INTO "outbox" (event_uuid, order_uuid) VALUES ($1, $2)`
+	snapshot := *order
+	snapshot.OrderUUID = orderUUID
+	snapshot.Products = make([]models.Product, 0, len(order.Products))
+	for _, product := range order.Products {
+		product.OrderUUID = orderUUID
+		snapshot.Products = append(snapshot.Products, product)
+	}
 
-	if _, err = tx.ExecContext(ctx, outboxQuery, eventUUID, orderUUID); err != nil {
+	if err = or.insertOutboxEvent(ctx, tx, models.EventTypeOrderCreated, orderUUID, &snapshot, initialOrderVersion); err != nil {
 		or.log.Error(op, slog.String("outbox insert error", err.Error()))
-		return uuid.Nil, fmt.Errorf("%s: outbox insert error: %w", op, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -100,10 +181,48 @@ func (or *OrderRepository) Create(
 	return
 }
 
-func (or *OrderRepository) Cancel(ctx context.Context, orderUUID uuid.UUID) (err error) {
+func (or *OrderRepository) Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error {
 	const op = "repository.order.Cancel"
 
-	tx, err := or.db.Begin()
+	return or.changeStatus(ctx, op, orderUUID, models.OrderStatusCanceled, change)
+}
+
+func (or *OrderRepository) MarkPaid(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "repository.order.MarkPaid"
+
+	return or.changeStatus(ctx, op, orderUUID, models.OrderStatusPaid, change)
+}
+
+func (or *OrderRepository) MarkDelivered(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "repository.order.MarkDelivered"
+
+	return or.changeStatus(ctx, op, orderUUID, models.OrderStatusDelivered, change)
+}
+
+// CancelTx - Cancel внутри транзакции tx вызывающей стороны, например
+// транзакции inbox. Коммит и откат tx остаются за вызывающей стороной.
+func (or *OrderRepository) CancelTx(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "repository.order.CancelTx"
+
+	return or.changeStatusTx(ctx, tx, op, orderUUID, models.OrderStatusCanceled, change)
+}
+
+// MarkPaidTx - MarkPaid внутри транзакции tx вызывающей стороны
+func (or *OrderRepository) MarkPaidTx(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "repository.order.MarkPaidTx"
+
+	return or.changeStatusTx(ctx, tx, op, orderUUID, models.OrderStatusPaid, change)
+}
+
+// changeStatus переводит заказ в статус to в собственной транзакции, см. changeStatusTx
+func (or *OrderRepository) changeStatus(
+	ctx context.Context,
+	op string,
+	orderUUID uuid.UUID,
+	to models.OrderStatus,
+	change models.StatusChange,
+) (err error) {
+	tx, err := or.db.BeginTx(ctx, nil)
 	if err != nil {
 		or.log.Error(op, slog.String("error", err.Error()))
 		return fmt.Errorf("%s: begin transaction: %w", op, err)
@@ -118,28 +237,178 @@ func (or *OrderRepository) Cancel(ctx context.Context, orderUUID uuid.UUID) (err
 		}
 	}()
 
-	const cancelQuery = `UPDATE "order" SET status = $1 WHERE uuid = $2`
+	if err = or.changeStatusTx(ctx, tx, op, orderUUID, to, change); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
 
-	_, err = tx.ExecContext(ctx, cancelQuery, int(models.OrderStatusCanceled), orderUUID)
+// changeStatusTx переводит заказ в статус to внутри tx, проверяя переход по
+// таблице models.orderStatusTransitions. Строка заказа блокируется до конца
+// транзакции, поэтому конкурентные переходы одного заказа выполняются
+// последовательно. Ошибки поиска заказа и проверки перехода возвращаются до
+// первой записи, так что после них tx остаётся пригодной для коммита.
+func (or *OrderRepository) changeStatusTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	op string,
+	orderUUID uuid.UUID,
+	to models.OrderStatus,
+	change models.StatusChange,
+) error {
+	from, err := or.lockStatus(ctx, tx, op, orderUUID)
 	if err != nil {
+		return err
+	}
+
+	return or.transition(ctx, tx, op, orderUUID, from, to, change)
+}
+
+// lockStatus блокирует строку заказа до конца транзакции и возвращает его статус
+func (or *OrderRepository) lockStatus(
+	ctx context.Context,
+	tx *sql.Tx,
+	op string,
+	orderUUID uuid.UUID,
+) (models.OrderStatus, error) {
+	const statusQuery = `SELECT status FROM "order" WHERE uuid = $1 FOR UPDATE`
+
+	var status models.OrderStatus
+	if err := tx.QueryRowContext(ctx, statusQuery, orderUUID).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UndefinedStatus, fmt.Errorf("%s: %w", op, internal_errors.ErrOrderNotFound)
+		}
+		or.log.Error(op, slog.String("error", err.Error()))
+		return models.UndefinedStatus, fmt.Errorf("%s: select status: %w", op, err)
+	}
+
+	return status, nil
+}
+
+// transition переводит заблокированный заказ из статуса from в to внутри tx:
+// обновляет статус и версию, пишет историю, проводит баллы и промокод и
+// кладёт событие в outbox
+func (or *OrderRepository) transition(
+	ctx context.Context,
+	tx *sql.Tx,
+	op string,
+	orderUUID uuid.UUID,
+	from, to models.OrderStatus,
+	change models.StatusChange,
+) error {
+	if err := from.ValidateTransition(to); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	const updateQuery = `
+							UPDATE "order" SET status = $1, updated_at = now(), version = version + 1
+								WHERE uuid = $2
+								RETURNING version
+						`
+
+	var version int
+	if err := tx.QueryRowContext(ctx, updateQuery, int(to), orderUUID).Scan(&version); err != nil {
 		or.log.Error(op, slog.String("error", err.Error()))
 		return fmt.Errorf("%s: execute statement: %w", op, err)
 	}
 
-	const outboxQuery = `INSERT INTO "outbox" (event_uuid, order_uuid) VALUES ($1, $2)`
+	if err := or.insertStatusHistory(ctx, tx, orderUUID, from, to, change); err != nil {
+		or.log.Error(op, slog.String("status history insert error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
-	eventUUID, err := uuid.NewUUID()
+	if err := settlePoints(ctx, tx, orderUUID, to); err != nil {
+		or.log.Error(op, slog.String("points settle error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if to == models.OrderStatusCanceled {
+		if err := returnPromo(ctx, tx, orderUUID); err != nil {
+			or.log.Error(op, slog.String("promo return error", err.Error()))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	snapshot, err := or.order(ctx, tx, op, orderUUID)
 	if err != nil {
-		or.log.Error(op, slog.String("outbox insert error", err.Error()))
-		return fmt.Errorf("%s: outbox insert error: %w", op, err)
+		return err
 	}
 
-	if _, err = tx.ExecContext(ctx, outboxQuery, eventUUID, orderUUID); err != nil {
+	if err = or.insertOutboxEvent(ctx, tx, models.EventTypeByStatus(to), orderUUID, snapshot, version); err != nil {
 		or.log.Error(op, slog.String("outbox insert error", err.Error()))
-		return fmt.Errorf("%s: outbox insert error: %w", op, err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (or *OrderRepository) insertStatusHistory(
+	ctx context.Context,
+	tx *sql.Tx,
+	orderUUID uuid.UUID,
+	from, to models.OrderStatus,
+	change models.StatusChange,
+) error {
+	const historyQuery = `
+							INSERT INTO "order_status_history" (order_uuid, from_status, to_status, actor, reason)
+								VALUES ($1, $2, $3, $4, $5)
+						`
+
+	var fromStatus sql.NullInt64
+	if from != models.UndefinedStatus {
+		fromStatus = sql.NullInt64{Int64: int64(from), Valid: true}
+	}
+
+	if _, err := tx.ExecContext(ctx, historyQuery, orderUUID, fromStatus, int(to), change.Actor, change.Reason); err != nil {
+		return fmt.Errorf("status history insert error: %w", err)
+	}
+
+	return nil
+}
+
+// insertOutboxEvent пишет событие в outbox в той же транзакции, что и изменение заказа.
+// В payload кладётся полный снимок заказа, version - версия агрегата после изменения.
+func (or *OrderRepository) insertOutboxEvent(
+	ctx context.Context,
+	tx *sql.Tx,
+	eventType models.EventType,
+	orderUUID uuid.UUID,
+	payload any,
+	version int,
+) error {
+	eventUUID, err := uuid.NewUUID()
+	if err != nil {
+		return fmt.Errorf("event_uuid generate error: %w", err)
+	}
+
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload error: %w", err)
+	}
+
+	const outboxQuery = `
+							INSERT INTO "outbox" (event_uuid, order_uuid, event_type, payload, aggregate_version)
+								VALUES ($1, $2, $3, $4, $5)
+						`
+
+	if _, err = tx.ExecContext(ctx, outboxQuery, eventUUID, orderUUID, eventType, payloadJSON, version); err != nil {
+		return fmt.Errorf("outbox insert error: %w", err)
+	}
+
+	// уведомление доставляется слушателям только после коммита транзакции
+	const notifyQuery = `SELECT pg_notify($1, '')`
+
+	if _, err = tx.ExecContext(ctx, notifyQuery, outbox.NotifyChannel); err != nil {
+		return fmt.Errorf("outbox notify error: %w", err)
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 func (or *OrderRepository) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) (map[uuid.UUID]models.Order, error) {
@@ -148,7 +417,9 @@ func (or *OrderRepository) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID)
 	ordersMap := make(map[uuid.UUID]models.Order, len(UUIDs))
 
 	const orderQuery = `
-							SELECT uuid, user_uuid, status, payment_type 
+							SELECT uuid, user_uuid, status, payment_type, currency,
+									total_amount, COALESCE(promo_code, ''), promo_discount,
+									points_discount, grand_total, with_points, version
 								FROM "order"
 								WHERE uuid = ANY($1)
 						`
@@ -162,7 +433,11 @@ func (or *OrderRepository) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID)
 
 	for rows.Next() {
 		var order models.Order
-		if err = rows.Scan(&order.OrderUUID, &order.UserUUID, &order.Status, &order.PaymentType); err != nil {
+		if err = rows.Scan(
+			&order.OrderUUID, &order.UserUUID, &order.Status, &order.PaymentType,
+			&order.Currency, &order.TotalAmount, &order.PromoCode, &order.PromoDiscount,
+			&order.PointsDiscount, &order.GrandTotal, &order.WithPoints, &order.Version,
+		); err != nil {
 			or.log.Error(op, slog.String("scan order error", err.Error()))
 			return nil, fmt.Errorf("%s: scan error: %w", op, err)
 		}
@@ -177,9 +452,10 @@ func (or *OrderRepository) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID)
 	}
 
 	const orderProductsQuery = `
-								SELECT order_uuid, product_uuid, amount
+								SELECT order_uuid, product_uuid, quantity, unit_price, currency
 									FROM "order_products"
-									WHERE order_uuid = ANY($1)
+									WHERE order_uuid = ANY($1) AND canceled_at IS NULL
+									ORDER BY order_product_id
 								`
 
 	rows, err = or.db.QueryContext(ctx, orderProductsQuery, pq.Array(UUIDs))
@@ -190,7 +466,7 @@ func (or *OrderRepository) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID)
 
 	for rows.Next() {
 		var product models.Product
-		if err = rows.Scan(&product.OrderUUID, &product.UUID, &product.Amount); err != nil {
+		if err = rows.Scan(&product.OrderUUID, &product.UUID, &product.Quantity, &product.UnitPrice, &product.Currency); err != nil {
 			or.log.Error(op, slog.String("scan order_products ", err.Error()))
 			return nil, fmt.Errorf("%s: scan error: %w", op, err)
 		}
@@ -228,48 +504,264 @@ func (or *OrderRepository) Status(ctx context.Context, orderUUID uuid.UUID) (int
 	return status, nil
 }
 
-func (or *OrderRepository) Order(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error) {
-	op := "repository.order.Order"
+// Versions возвращает текущие версии заказов. Заказов, которых нет, в ответе нет.
+func (or *OrderRepository) Versions(ctx context.Context, UUIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	const op = "repository.order.Versions"
 
-	const orderQuery = `SELECT o.uuid, o.user_uuid, o.status, o.payment_type FROM "order" o where o.uuid = $1`
+	const query = `SELECT uuid, version FROM "order" WHERE uuid = ANY($1)`
 
-	stmt, err := or.db.PrepareContext(ctx, orderQuery)
+	rows, err := or.db.QueryContext(ctx, query, pq.Array(UUIDs))
 	if err != nil {
-		or.log.Error(op, slog.String("prepare statement error", err.Error()))
-		return nil, err
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
 	}
+	defer rows.Close()
 
-	row := stmt.QueryRowContext(ctx, orderUUID)
+	versions := make(map[uuid.UUID]int, len(UUIDs))
+	for rows.Next() {
+		var (
+			orderUUID uuid.UUID
+			version   int
+		)
+		if err = rows.Scan(&orderUUID, &version); err != nil {
+			or.log.Error(op, slog.String("scan version error", err.Error()))
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		versions[orderUUID] = version
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return versions, nil
+}
+
+func (or *OrderRepository) Order(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error) {
+	const op = "repository.order.Order"
+
+	return or.order(ctx, or.db, op, orderUUID)
+}
+
+// querier - общее подмножество *sqlx.DB и *sql.Tx, чтобы читать заказ как
+// отдельным запросом, так и внутри уже открытой транзакции
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (or *OrderRepository) order(ctx context.Context, q querier, op string, orderUUID uuid.UUID) (*models.Order, error) {
+	const orderQuery = `
+							SELECT o.uuid, o.user_uuid, o.status, o.payment_type, o.currency,
+									o.total_amount, COALESCE(o.promo_code, ''), o.promo_discount,
+									o.points_discount, o.grand_total, o.with_points, o.version
+								FROM "order" o
+								WHERE o.uuid = $1
+						`
+
+	row := q.QueryRowContext(ctx, orderQuery, orderUUID)
 
 	var order models.Order
-	if err = row.Scan(&order.OrderUUID, &order.UserUUID, &order.Status, &order.PaymentType); err != nil {
+	if err := row.Scan(
+		&order.OrderUUID, &order.UserUUID, &order.Status, &order.PaymentType,
+		&order.Currency, &order.TotalAmount, &order.PromoCode, &order.PromoDiscount,
+		&order.PointsDiscount, &order.GrandTotal, &order.WithPoints, &order.Version,
+	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, internal_errors.ErrOrderNotFound
 		}
 		or.log.Error(op, slog.String("scan order error", err.Error()))
-		return nil, err
+		return nil, fmt.Errorf("%s: scan error: %w", op, err)
 	}
 
 	const orderProductsQuery = `
-									SELECT op.order_uuid, op.product_uuid, op.amount 
-										FROM "order_products" op 
-										WHERE op.order_uuid = $1
+									SELECT op.order_uuid, op.product_uuid, op.quantity, op.unit_price, op.currency
+										FROM "order_products" op
+										WHERE op.order_uuid = $1 AND op.canceled_at IS NULL
+										ORDER BY op.order_product_id
 								`
 
-	rows, err := or.db.QueryContext(ctx, orderProductsQuery, orderUUID)
+	rows, err := q.QueryContext(ctx, orderProductsQuery, orderUUID)
 	if err != nil {
 		or.log.Error(op, slog.String("execute statement error", err.Error()))
 		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var product models.Product
-		if err = rows.Scan(&product.OrderUUID, &product.UUID, &product.Amount); err != nil {
+		if err = rows.Scan(&product.OrderUUID, &product.UUID, &product.Quantity, &product.UnitPrice, &product.Currency); err != nil {
 			or.log.Error(op, slog.String("scan order_products ", err.Error()))
 			return nil, fmt.Errorf("%s: scan error: %w", op, err)
 		}
 		order.Products = append(order.Products, product)
 	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
 
 	return &order, nil
 }
+
+// UserOrders возвращает страницу заказов пользователя, отсортированных по
+// (created_at, uuid). Страница продолжается после filter.After; чтобы узнать,
+// есть ли следующая, читается на один заказ больше filter.Limit.
+func (or *OrderRepository) UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error) {
+	const op = "repository.order.UserOrders"
+
+	conditions := []string{"user_uuid = $1"}
+	args := []interface{}{filter.UserUUID}
+
+	addCondition := func(condition string, values ...interface{}) {
+		placeholders := make([]interface{}, 0, len(values))
+		for _, value := range values {
+			args = append(args, value)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]int64, 0, len(filter.Statuses))
+		for _, status := range filter.Statuses {
+			statuses = append(statuses, int64(status))
+		}
+		addCondition("status = ANY(%s)", pq.Array(statuses))
+	}
+	if filter.PaymentType != models.UndefinedType {
+		addCondition("payment_type = %s", filter.PaymentType)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		addCondition("created_at >= %s", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		addCondition("created_at < %s", filter.CreatedTo)
+	}
+	if filter.After != nil {
+		addCondition("(created_at, uuid) > (%s, %s)", filter.After.CreatedAt, filter.After.OrderUUID)
+	}
+
+	args = append(args, filter.Limit+1)
+	query := fmt.Sprintf(`
+							SELECT uuid, user_uuid, status, payment_type, currency,
+									total_amount, COALESCE(promo_code, ''), promo_discount,
+									points_discount, grand_total, with_points, created_at
+								FROM "order"
+								WHERE %s
+								ORDER BY created_at, uuid
+								LIMIT $%d
+						`, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := or.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	page := &models.OrdersPage{Orders: make([]models.Order, 0, filter.Limit)}
+	var last models.OrderCursor
+	for rows.Next() {
+		if len(page.Orders) == filter.Limit {
+			page.NextCursor = &last
+			break
+		}
+
+		var order models.Order
+		if err = rows.Scan(
+			&order.OrderUUID, &order.UserUUID, &order.Status, &order.PaymentType,
+			&order.Currency, &order.TotalAmount, &order.PromoCode, &order.PromoDiscount,
+			&order.PointsDiscount, &order.GrandTotal, &order.WithPoints,
+			&last.CreatedAt,
+		); err != nil {
+			or.log.Error(op, slog.String("scan order error", err.Error()))
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		last.OrderUUID = order.OrderUUID
+		page.Orders = append(page.Orders, order)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	if len(page.Orders) == 0 {
+		return page, nil
+	}
+
+	orderUUIDs := make([]uuid.UUID, 0, len(page.Orders))
+	for _, order := range page.Orders {
+		orderUUIDs = append(orderUUIDs, order.OrderUUID)
+	}
+
+	const orderProductsQuery = `
+								SELECT order_uuid, product_uuid, quantity, unit_price, currency
+									FROM "order_products"
+									WHERE order_uuid = ANY($1) AND canceled_at IS NULL
+									ORDER BY order_product_id
+								`
+
+	productRows, err := or.db.QueryContext(ctx, orderProductsQuery, pq.Array(orderUUIDs))
+	if err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer productRows.Close()
+
+	products := make(map[uuid.UUID][]models.Product, len(page.Orders))
+	for productRows.Next() {
+		var product models.Product
+		if err = productRows.Scan(&product.OrderUUID, &product.UUID, &product.Quantity, &product.UnitPrice, &product.Currency); err != nil {
+			or.log.Error(op, slog.String("scan order_products ", err.Error()))
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		products[product.OrderUUID] = append(products[product.OrderUUID], product)
+	}
+	if productRows.Err() != nil {
+		return nil, productRows.Err()
+	}
+
+	for i := range page.Orders {
+		page.Orders[i].Products = products[page.Orders[i].OrderUUID]
+	}
+
+	return page, nil
+}
+
+func (or *OrderRepository) StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	const op = "repository.order.StatusHistory"
+
+	const historyQuery = `
+							SELECT order_uuid, COALESCE(from_status, 0), to_status, actor, reason, changed_at
+								FROM "order_status_history"
+								WHERE order_uuid = $1
+								ORDER BY id
+						`
+
+	rows, err := or.db.QueryContext(ctx, historyQuery, orderUUID)
+	if err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var history []models.StatusHistoryEntry
+	for rows.Next() {
+		var entry models.StatusHistoryEntry
+		if err = rows.Scan(
+			&entry.OrderUUID, &entry.FromStatus, &entry.ToStatus, &entry.Actor, &entry.Reason, &entry.ChangedAt,
+		); err != nil {
+			or.log.Error(op, slog.String("scan status history error", err.Error()))
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		history = append(history, entry)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// у каждого заказа есть как минимум запись о создании
+	if len(history) == 0 {
+		return nil, internal_errors.ErrOrderNotFound
+	}
+
+	return history, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+func TestCreateStoresMoney(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	order := &models.Order{
+		UserUUID:    uuid.New(),
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Points,
+		WithPoints:  500,
+		Products: []models.Product{
+			{UUID: uuid.New(), Quantity: 3, UnitPrice: 1999, Currency: "USD"},
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: models.MaxAmount - 5997, Currency: "USD"},
+		},
+	}
+	require.NoError(t, order.CalculateTotals())
+
+	orderUUID, err := repo.Create(ctx, order)
+	require.NoError(t, err)
+
+	stored, err := repo.Order(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, "USD", stored.Currency)
+	require.Equal(t, uint64(models.MaxAmount), stored.TotalAmount)
+	require.Equal(t, uint64(500), stored.PointsDiscount)
+	require.Equal(t, uint64(models.MaxAmount-500), stored.GrandTotal)
+	require.Equal(t, 500, stored.WithPoints)
+	require.Len(t, stored.Products, 2)
+	require.Equal(t, uint32(3), stored.Products[0].Quantity)
+	require.Equal(t, uint64(1999), stored.Products[0].UnitPrice)
+	require.Equal(t, "USD", stored.Products[0].Currency)
+
+	var payload []byte
+	require.NoError(t, db.Get(&payload, `SELECT payload FROM "outbox" WHERE order_uuid = $1`, orderUUID))
+
+	var snapshot models.Order
+	require.NoError(t, json.Unmarshal(payload, &snapshot))
+	require.Equal(t, stored.TotalAmount, snapshot.TotalAmount)
+	require.Equal(t, "USD", snapshot.Currency)
+}
+
+func TestTotalsMismatches(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	consistent := createOrderAt(t, repo, db, userUUID, models.Card, time.Now())
+	wrongTotal := createOrderAt(t, repo, db, userUUID, models.Card, time.Now())
+	wrongCurrency := createOrderAt(t, repo, db, userUUID, models.Card, time.Now())
+
+	_, err := db.Exec(`UPDATE "order" SET total_amount = total_amount + 1 WHERE uuid = $1`, wrongTotal)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE "order_products" SET currency = 'USD' WHERE order_uuid = $1`, wrongCurrency)
+	require.NoError(t, err)
+
+	var found []uuid.UUID
+	after := uuid.Nil
+	for {
+		mismatches, err := repo.TotalsMismatches(ctx, after, 1)
+		require.NoError(t, err)
+		if len(mismatches) == 0 {
+			break
+		}
+
+		require.Len(t, mismatches, 1)
+		found = append(found, mismatches[0].OrderUUID)
+		after = mismatches[0].OrderUUID
+
+		if mismatches[0].OrderUUID == wrongCurrency {
+			require.Equal(t, "USD", mismatches[0].LinesCurrency)
+		}
+		if mismatches[0].OrderUUID == wrongTotal {
+			require.Equal(t, "100", mismatches[0].LinesTotal)
+			require.Equal(t, uint64(101), mismatches[0].TotalAmount)
+		}
+	}
+
+	require.ElementsMatch(t, []uuid.UUID{wrongTotal, wrongCurrency}, found)
+	require.NotContains(t, found, consistent)
+}
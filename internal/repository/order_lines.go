@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// CancelLines отменяет строки заказа с товарами productUUIDs: помечает их
+// отменёнными, сохраняет пересчитанные суммы и строку скидки, возвращает
+// лишние баллы и кладёт в outbox событие OrderLinesCanceled. Если отменена
+// последняя строка, заказ в той же транзакции переходит в Canceled с событием
+// OrderCanceled.
+func (or *OrderRepository) CancelLines(
+	ctx context.Context,
+	orderUUID uuid.UUID,
+	productUUIDs []uuid.UUID,
+	change models.StatusChange,
+) (_ *models.LinesCancellation, err error) {
+	const op = "repository.order.CancelLines"
+
+	tx, err := or.db.BeginTx(ctx, nil)
+	if err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		}
+	}()
+
+	from, err := or.lockStatus(ctx, tx, op, orderUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := or.order(ctx, tx, op, orderUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var promo *models.PromoCode
+	if order.PromoCode != "" {
+		if promo, err = selectPromoCode(ctx, tx, order.PromoCode); err != nil {
+			or.log.Error(op, slog.String("error", err.Error()))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	cancellation, err := order.CancelLines(productUUIDs, promo)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	const linesQuery = `
+						UPDATE "order_products" SET canceled_at = now()
+							WHERE order_uuid = $1 AND product_uuid = ANY($2) AND canceled_at IS NULL
+					`
+
+	if _, err = tx.ExecContext(ctx, linesQuery, orderUUID, pq.Array(productUUIDs)); err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: cancel lines: %w", op, err)
+	}
+
+	// при отмене заказа целиком удержание закрывает transition
+	if cancellation.PointsRefund > 0 && cancellation.Status != models.OrderStatusCanceled {
+		if err = reducePoints(ctx, tx, orderUUID, cancellation.PointsRefund); err != nil {
+			or.log.Error(op, slog.String("points reduce error", err.Error()))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err = updatePromoDiscount(ctx, tx, orderUUID, order.PromoDiscount); err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	const totalsQuery = `
+						UPDATE "order" SET total_amount = $2, promo_discount = $3, points_discount = $4,
+								grand_total = $5, with_points = $6, updated_at = now(), version = version + 1
+							WHERE uuid = $1
+							RETURNING version
+					`
+
+	var version int
+	if err = tx.QueryRowContext(ctx, totalsQuery, orderUUID,
+		order.TotalAmount, order.PromoDiscount, order.PointsDiscount, order.GrandTotal, order.WithPoints,
+	).Scan(&version); err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: update totals: %w", op, err)
+	}
+
+	event := models.LinesCanceledEvent{Order: *order, LinesCanceled: cancellation}
+	if err = or.insertOutboxEvent(ctx, tx, models.EventTypeOrderLinesCanceled, orderUUID, &event, version); err != nil {
+		or.log.Error(op, slog.String("outbox insert error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cancellation.Status == models.OrderStatusCanceled {
+		if err = or.transition(ctx, tx, op, orderUUID, from, models.OrderStatusCanceled, change); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return &cancellation, nil
+}
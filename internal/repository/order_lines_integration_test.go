@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func TestCancelLines(t *testing.T) {
+	db := testdb.New(t)
+	testdb.Truncate(t, db,
+		"order", "order_products", "order_status_history", "outbox",
+		"points_account", "points_hold", "points_ledger", "promo_code", "promo_code_usage", "order_discount",
+	)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	orders, points, promos := NewOrderRepository(log, db), NewPointsRepository(log, db), NewPromoRepository(log, db)
+	ctx := context.Background()
+
+	promo := models.PromoCode{
+		Code: "TEN", Kind: models.PromoPercentage, Percent: 10,
+		ValidFrom: time.Now().Add(-time.Hour), ValidTo: time.Now().Add(time.Hour), PerUserLimit: 1,
+	}
+	require.NoError(t, promos.CreatePromoCode(ctx, promo))
+
+	userUUID := uuid.New()
+	require.NoError(t, points.Credit(ctx, userUUID, 5000, "welcome bonus"))
+
+	order := &models.Order{
+		UserUUID:    userUUID,
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Points,
+		WithPoints:  5000,
+		Products: []models.Product{
+			{UUID: uuid.New(), Quantity: 5, UnitPrice: 1000, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 1, UnitPrice: 3000, Currency: "RUB"},
+			{UUID: uuid.New(), Quantity: 2, UnitPrice: 1000, Currency: "RUB"},
+		},
+	}
+	require.NoError(t, order.ApplyPromo(promo, time.Now()))
+
+	orderUUID, err := orders.Create(ctx, order)
+	require.NoError(t, err)
+	change := models.StatusChange{Actor: models.ActorCustomer}
+	require.NoError(t, orders.MarkPaid(ctx, orderUUID, change))
+	requireBalance(t, points, userUUID, 0, 0)
+
+	_, err = orders.CancelLines(ctx, orderUUID, []uuid.UUID{uuid.New()}, change)
+	require.ErrorIs(t, err, internal_errors.ErrOrderLineNotFound)
+
+	// баллов больше новой суммы - лишние возвращаются на баланс
+	cancellation, err := orders.CancelLines(ctx, orderUUID, []uuid.UUID{order.Products[0].UUID}, change)
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusPaid, cancellation.Status)
+	require.Equal(t, uint64(4000), cancellation.RefundAmount)
+	require.Equal(t, uint64(500), cancellation.PointsRefund)
+	requireBalance(t, points, userUUID, 500, 0)
+
+	stored, err := orders.Order(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Len(t, stored.Products, 2)
+	require.Equal(t, uint64(5000), stored.TotalAmount)
+	require.Equal(t, uint64(500), stored.PromoDiscount)
+	require.Equal(t, uint64(4500), stored.PointsDiscount)
+	require.Zero(t, stored.GrandTotal)
+
+	var promoLine uint64
+	require.NoError(t, db.Get(&promoLine, `SELECT amount FROM "order_discount" WHERE order_uuid = $1`, orderUUID))
+	require.Equal(t, uint64(500), promoLine)
+
+	mismatches, err := orders.TotalsMismatches(ctx, uuid.Nil, 10)
+	require.NoError(t, err)
+	require.Empty(t, mismatches)
+
+	var payload []byte
+	require.NoError(t, db.Get(&payload,
+		`SELECT payload FROM "outbox" WHERE order_uuid = $1 AND event_type = $2`,
+		orderUUID, models.EventTypeOrderLinesCanceled))
+
+	var event models.LinesCanceledEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Equal(t, uint64(4000), event.LinesCanceled.RefundAmount)
+	require.Len(t, event.Products, 2)
+
+	// последняя строка отменяет заказ, баллы и промокод возвращаются
+	_, err = orders.CancelLines(ctx, orderUUID, []uuid.UUID{order.Products[1].UUID, order.Products[2].UUID}, change)
+	require.NoError(t, err)
+	requireBalance(t, points, userUUID, 5000, 0)
+
+	status, err := orders.Status(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, int(models.OrderStatusCanceled), status)
+
+	var used int
+	require.NoError(t, db.Get(&used, `SELECT used FROM "promo_code_usage" WHERE code = $1 AND user_uuid = $2`,
+		promo.Code, userUUID))
+	require.Zero(t, used)
+
+	_, err = orders.CancelLines(ctx, orderUUID, []uuid.UUID{order.Products[0].UUID}, change)
+	require.ErrorIs(t, err, internal_errors.ErrInvalidStatusTransition)
+}
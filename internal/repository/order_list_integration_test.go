@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func newOrderListTest(t *testing.T) (*OrderRepository, *sqlx.DB) {
+	t.Helper()
+
+	db := testdb.New(t)
+	testdb.Truncate(t, db, "order", "order_products", "order_status_history", "outbox")
+
+	return NewOrderRepository(slog.New(slog.NewTextHandler(io.Discard, nil)), db), db
+}
+
+// createOrderAt создаёт заказ и переписывает его created_at
+func createOrderAt(
+	t *testing.T,
+	repo *OrderRepository,
+	db *sqlx.DB,
+	userUUID uuid.UUID,
+	paymentType models.PaymentType,
+	createdAt time.Time,
+) uuid.UUID {
+	t.Helper()
+
+	orderUUID, err := repo.Create(context.Background(), &models.Order{
+		UserUUID:    userUUID,
+		Status:      models.OrderStatusCreated,
+		PaymentType: paymentType,
+		Products:    []models.Product{{UUID: uuid.New(), Quantity: 1, UnitPrice: 100, Currency: "RUB"}},
+		Currency:    "RUB",
+		TotalAmount: 100,
+		GrandTotal:  100,
+	})
+	require.NoError(t, err)
+
+	_, err = db.Exec(`UPDATE "order" SET created_at = $2 WHERE uuid = $1`, orderUUID, createdAt)
+	require.NoError(t, err)
+
+	return orderUUID
+}
+
+func pageUUIDs(page *models.OrdersPage) []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(page.Orders))
+	for _, order := range page.Orders {
+		result = append(result, order.OrderUUID)
+	}
+
+	return result
+}
+
+func TestUserOrdersPagination(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// два заказа с одинаковым created_at упорядочиваются по uuid
+	var expected []uuid.UUID
+	for i := 0; i < 5; i++ {
+		expected = append(expected, createOrderAt(t, repo, db, userUUID, models.Card, start.Add(time.Duration(i/2)*time.Hour)))
+	}
+	for i := 0; i < len(expected); i += 2 {
+		if i+1 < len(expected) && expected[i+1].String() < expected[i].String() {
+			expected[i], expected[i+1] = expected[i+1], expected[i]
+		}
+	}
+	createOrderAt(t, repo, db, uuid.New(), models.Card, start)
+
+	var got []uuid.UUID
+	filter := models.OrderFilter{UserUUID: userUUID, Limit: 2}
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+
+		page, err := repo.UserOrders(ctx, filter)
+		require.NoError(t, err)
+		got = append(got, pageUUIDs(page)...)
+
+		for _, order := range page.Orders {
+			require.Len(t, order.Products, 1)
+		}
+
+		if page.NextCursor == nil {
+			break
+		}
+		filter.After = page.NextCursor
+	}
+
+	require.Equal(t, expected, got)
+}
+
+func TestUserOrdersFilters(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	early := createOrderAt(t, repo, db, userUUID, models.Card, start)
+	points := createOrderAt(t, repo, db, userUUID, models.Points, start.Add(time.Hour))
+	canceled := createOrderAt(t, repo, db, userUUID, models.Card, start.Add(2*time.Hour))
+	require.NoError(t, repo.Cancel(ctx, canceled, models.StatusChange{Actor: models.ActorCustomer}))
+
+	tCases := []struct {
+		name     string
+		filter   models.OrderFilter
+		expected []uuid.UUID
+	}{
+		{
+			name:     "status",
+			filter:   models.OrderFilter{Statuses: []models.OrderStatus{models.OrderStatusCanceled}},
+			expected: []uuid.UUID{canceled},
+		},
+		{
+			name:     "payment_type",
+			filter:   models.OrderFilter{PaymentType: models.Points},
+			expected: []uuid.UUID{points},
+		},
+		{
+			name:     "created_range",
+			filter:   models.OrderFilter{CreatedFrom: start, CreatedTo: start.Add(2 * time.Hour)},
+			expected: []uuid.UUID{early, points},
+		},
+		{
+			name: "combined",
+			filter: models.OrderFilter{
+				Statuses:    []models.OrderStatus{models.OrderStatusCreated, models.OrderStatusCanceled},
+				PaymentType: models.Card,
+				CreatedFrom: start.Add(time.Minute),
+			},
+			expected: []uuid.UUID{canceled},
+		},
+	}
+
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			tCase.filter.UserUUID = userUUID
+			tCase.filter.Limit = 10
+
+			page, err := repo.UserOrders(ctx, tCase.filter)
+			require.NoError(t, err)
+			require.Equal(t, tCase.expected, pageUUIDs(page))
+			require.Nil(t, page.NextCursor)
+		})
+	}
+}
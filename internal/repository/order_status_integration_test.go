@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+func TestStatusHistory(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	orderUUID := createOrderAt(t, repo, db, userUUID, models.Card, time.Now())
+
+	require.NoError(t, repo.MarkPaid(ctx, orderUUID, models.StatusChange{Actor: models.ActorPayment, Reason: "payment succeeded"}))
+	require.NoError(t, repo.Cancel(ctx, orderUUID, models.StatusChange{Actor: models.ActorCustomer, Reason: "changed my mind"}))
+
+	// отклонённый переход в историю не попадает
+	err := repo.MarkDelivered(ctx, orderUUID, models.StatusChange{Actor: models.ActorSystem})
+	require.ErrorIs(t, err, internal_errors.ErrInvalidStatusTransition)
+
+	history, err := repo.StatusHistory(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	type step struct {
+		from, to      models.OrderStatus
+		actor, reason string
+	}
+	steps := make([]step, 0, len(history))
+	for i, entry := range history {
+		require.Equal(t, orderUUID, entry.OrderUUID)
+		require.False(t, entry.ChangedAt.IsZero())
+		if i > 0 {
+			require.False(t, entry.ChangedAt.Before(history[i-1].ChangedAt))
+		}
+		steps = append(steps, step{entry.FromStatus, entry.ToStatus, entry.Actor, entry.Reason})
+	}
+
+	require.Equal(t, []step{
+		{models.UndefinedStatus, models.OrderStatusCreated, userUUID.String(), "order created"},
+		{models.OrderStatusCreated, models.OrderStatusPaid, models.ActorPayment, "payment succeeded"},
+		{models.OrderStatusPaid, models.OrderStatusCanceled, models.ActorCustomer, "changed my mind"},
+	}, steps)
+
+	_, err = repo.StatusHistory(ctx, uuid.New())
+	require.ErrorIs(t, err, internal_errors.ErrOrderNotFound)
+}
+
+func TestMarkPaidMarkDelivered(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	orderUUID := createOrderAt(t, repo, db, uuid.New(), models.Card, time.Now())
+	change := models.StatusChange{Actor: models.ActorSystem}
+
+	requireStatus := func(want models.OrderStatus) {
+		t.Helper()
+
+		status, err := repo.Status(ctx, orderUUID)
+		require.NoError(t, err)
+		require.Equal(t, int(want), status)
+	}
+
+	// доставить можно только оплаченный заказ
+	err := repo.MarkDelivered(ctx, orderUUID, change)
+	require.ErrorIs(t, err, internal_errors.ErrOrderNotPaid)
+	requireStatus(models.OrderStatusCreated)
+
+	require.NoError(t, repo.MarkPaid(ctx, orderUUID, change))
+	requireStatus(models.OrderStatusPaid)
+
+	require.ErrorIs(t, repo.MarkPaid(ctx, orderUUID, change), internal_errors.ErrOrderAlreadyPaid)
+	requireStatus(models.OrderStatusPaid)
+
+	require.NoError(t, repo.MarkDelivered(ctx, orderUUID, change))
+	requireStatus(models.OrderStatusDelivered)
+
+	require.ErrorIs(t, repo.MarkDelivered(ctx, orderUUID, change), internal_errors.ErrOrderAlreadyDelivered)
+	require.ErrorIs(t, repo.MarkPaid(ctx, orderUUID, change), internal_errors.ErrOrderAlreadyDelivered)
+	require.ErrorIs(t, repo.Cancel(ctx, orderUUID, change), internal_errors.ErrOrderAlreadyDelivered)
+	requireStatus(models.OrderStatusDelivered)
+
+	canceled := createOrderAt(t, repo, db, uuid.New(), models.Card, time.Now())
+	require.NoError(t, repo.Cancel(ctx, canceled, change))
+	require.ErrorIs(t, repo.MarkPaid(ctx, canceled, change), internal_errors.ErrOrderAlreadyCanceled)
+
+	require.ErrorIs(t, repo.MarkPaid(ctx, uuid.New(), change), internal_errors.ErrOrderNotFound)
+}
+
+func TestOutboxEventColumns(t *testing.T) {
+	repo, db := newOrderListTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	orderUUID := createOrderAt(t, repo, db, userUUID, models.Card, time.Now())
+	require.NoError(t, repo.Cancel(ctx, orderUUID, models.StatusChange{Actor: models.ActorCustomer}))
+
+	var rows []struct {
+		EventType        string    `db:"event_type"`
+		Payload          []byte    `db:"payload"`
+		AggregateVersion int       `db:"aggregate_version"`
+		CreatedAt        time.Time `db:"created_at"`
+	}
+	require.NoError(t, db.Select(&rows, `
+		SELECT event_type, payload, aggregate_version, created_at FROM "outbox"
+			WHERE order_uuid = $1
+			ORDER BY id`, orderUUID))
+	require.Len(t, rows, 2)
+
+	tCases := []struct {
+		eventType models.EventType
+		version   int
+		status    models.OrderStatus
+	}{
+		{eventType: models.EventTypeOrderCreated, version: 1, status: models.OrderStatusCreated},
+		{eventType: models.EventTypeOrderCanceled, version: 2, status: models.OrderStatusCanceled},
+	}
+
+	for i, tCase := range tCases {
+		row := rows[i]
+		require.Equal(t, string(tCase.eventType), row.EventType)
+		require.Equal(t, tCase.version, row.AggregateVersion)
+		require.False(t, row.CreatedAt.IsZero())
+
+		// payload - полный снимок заказа после изменения
+		var snapshot models.Order
+		require.NoError(t, json.Unmarshal(row.Payload, &snapshot))
+		require.Equal(t, orderUUID, snapshot.OrderUUID)
+		require.Equal(t, userUUID, snapshot.UserUUID)
+		require.Equal(t, tCase.status, snapshot.Status)
+		require.Equal(t, models.Card, snapshot.PaymentType)
+		require.Equal(t, uint64(100), snapshot.GrandTotal)
+		require.Len(t, snapshot.Products, 1)
+		require.Equal(t, orderUUID, snapshot.Products[0].OrderUUID)
+	}
+}
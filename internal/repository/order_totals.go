@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+// TotalsMismatches возвращает до limit заказов с uuid больше after, у которых
+// хранимые суммы не сходятся со строками: сумма строк отличается от
+// total_amount, grand_total - от total_amount - promo_discount - points_discount,
+// строка скидки по промокоду - от promo_discount или валюта строк - от валюты
+// заказа. Отменённые строки не учитываются.
+// Строки суммируются в numeric, без переполнения.
+func (or *OrderRepository) TotalsMismatches(
+	ctx context.Context,
+	after uuid.UUID,
+	limit int,
+) ([]models.TotalsMismatch, error) {
+	const op = "repository.order.TotalsMismatches"
+
+	const query = `
+					SELECT o.uuid, o.currency, o.total_amount, o.promo_discount, COALESCE(d.amount, 0),
+						o.points_discount, o.grand_total, COALESCE(l.lines_total, 0)::text, COALESCE(l.currencies, '')
+						FROM "order" o
+						LEFT JOIN "order_discount" d ON d.order_uuid = o.uuid AND d.kind = 'promo_code'
+						LEFT JOIN LATERAL (
+							SELECT sum(op.unit_price::numeric * op.quantity) AS lines_total,
+								string_agg(DISTINCT op.currency::text, ',') AS currencies
+								FROM "order_products" op
+								WHERE op.order_uuid = o.uuid AND op.canceled_at IS NULL
+						) l ON true
+						WHERE o.uuid > $1
+							AND (
+								o.total_amount <> COALESCE(l.lines_total, 0)
+								OR o.grand_total <> o.total_amount - o.promo_discount - o.points_discount
+								OR o.promo_discount <> COALESCE(d.amount, 0)
+								OR COALESCE(l.currencies, o.currency::text) <> o.currency::text
+							)
+						ORDER BY o.uuid
+						LIMIT $2
+				`
+
+	rows, err := or.db.QueryContext(ctx, query, after, limit)
+	if err != nil {
+		or.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+	defer rows.Close()
+
+	var mismatches []models.TotalsMismatch
+	for rows.Next() {
+		var m models.TotalsMismatch
+		if err = rows.Scan(
+			&m.OrderUUID, &m.Currency, &m.TotalAmount, &m.PromoDiscount, &m.PromoDiscountLine,
+			&m.PointsDiscount, &m.GrandTotal, &m.LinesTotal, &m.LinesCurrency,
+		); err != nil {
+			or.log.Error(op, slog.String("scan totals mismatch error", err.Error()))
+			return nil, fmt.Errorf("%s: scan error: %w", op, err)
+		}
+		mismatches = append(mismatches, m)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return mismatches, nil
+}
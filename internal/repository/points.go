@@ -0,0 +1,292 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// PointsRepository - журнал баллов лояльности. Удержание, списание и возврат
+// баллов по заказу выполняются в транзакциях OrderRepository, см. holdPoints
+// и settlePoints.
+type PointsRepository struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewPointsRepository(log *slog.Logger, db *sqlx.DB) *PointsRepository {
+	return &PointsRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// Balance возвращает баланс пользователя. У пользователя без начислений
+// баланс нулевой.
+func (pr *PointsRepository) Balance(ctx context.Context, userUUID uuid.UUID) (*models.PointsBalance, error) {
+	const op = "repository.points.Balance"
+
+	const query = `SELECT balance, held FROM "points_account" WHERE user_uuid = $1`
+
+	balance := &models.PointsBalance{UserUUID: userUUID}
+	if err := pr.db.QueryRowContext(ctx, query, userUUID).Scan(&balance.Balance, &balance.Held); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return balance, nil
+		}
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return nil, fmt.Errorf("%s: scan error: %w", op, err)
+	}
+	balance.Available = balance.Balance - balance.Held
+
+	return balance, nil
+}
+
+// Credit начисляет пользователю amount баллов
+func (pr *PointsRepository) Credit(ctx context.Context, userUUID uuid.UUID, amount uint64, reason string) (err error) {
+	const op = "repository.points.Credit"
+
+	tx, err := pr.db.BeginTx(ctx, nil)
+	if err != nil {
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				err = errors.Join(err, rollbackErr)
+			}
+		}
+	}()
+
+	const accountQuery = `
+							INSERT INTO "points_account" (user_uuid, balance) VALUES ($1, $2)
+								ON CONFLICT (user_uuid) DO UPDATE
+									SET balance = "points_account".balance + EXCLUDED.balance, updated_at = now()
+						`
+
+	if _, err = tx.ExecContext(ctx, accountQuery, userUUID, amount); err != nil {
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: update account: %w", op, err)
+	}
+
+	if err = insertPointsEntry(ctx, tx, userUUID, uuid.NullUUID{}, models.PointsEntryCredit, amount, reason); err != nil {
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return nil
+}
+
+// holdPoints удерживает amount баллов пользователя под заказ. Условие на
+// доступный остаток проверяется в том же UPDATE, что и удержание, поэтому
+// конкурентные заказы не могут удержать больше баланса.
+func holdPoints(ctx context.Context, tx *sql.Tx, userUUID, orderUUID uuid.UUID, amount uint64) error {
+	const holdQuery = `
+						UPDATE "points_account" SET held = held + $2, updated_at = now()
+							WHERE user_uuid = $1 AND balance - held >= $2
+					`
+
+	result, err := tx.ExecContext(ctx, holdQuery, userUUID, amount)
+	if err != nil {
+		return fmt.Errorf("hold points error: %w", err)
+	}
+
+	held, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("hold points error: %w", err)
+	}
+	if held == 0 {
+		return internal_errors.ErrInsufficientPoints
+	}
+
+	const orderHoldQuery = `
+							INSERT INTO "points_hold" (order_uuid, user_uuid, amount, status)
+								VALUES ($1, $2, $3, $4)
+						`
+
+	if _, err = tx.ExecContext(ctx, orderHoldQuery, orderUUID, userUUID, amount, models.PointsHoldHeld); err != nil {
+		return fmt.Errorf("points hold insert error: %w", err)
+	}
+
+	return insertPointsEntry(ctx, tx, userUUID, uuid.NullUUID{UUID: orderUUID, Valid: true},
+		models.PointsEntryHold, amount, "order created")
+}
+
+// settlePoints проводит удержание баллов заказа при смене его статуса: при
+// оплате удержанные баллы списываются, при отмене удержание снимается, а уже
+// списанные баллы возвращаются. Заказ без удержания пропускается.
+func settlePoints(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, to models.OrderStatus) error {
+	switch to {
+	case models.OrderStatusPaid:
+		return capturePoints(ctx, tx, orderUUID)
+	case models.OrderStatusCanceled:
+		return returnPoints(ctx, tx, orderUUID)
+	default:
+		return nil
+	}
+}
+
+func capturePoints(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID) error {
+	const holdQuery = `
+						UPDATE "points_hold" SET status = $2, updated_at = now()
+							WHERE order_uuid = $1 AND status = $3
+							RETURNING user_uuid, amount
+					`
+
+	var userUUID uuid.UUID
+	var amount uint64
+	err := tx.QueryRowContext(ctx, holdQuery, orderUUID, models.PointsHoldCaptured, models.PointsHoldHeld).
+		Scan(&userUUID, &amount)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("capture points error: %w", err)
+	}
+
+	const accountQuery = `
+							UPDATE "points_account" SET balance = balance - $2, held = held - $2, updated_at = now()
+								WHERE user_uuid = $1
+						`
+
+	if _, err = tx.ExecContext(ctx, accountQuery, userUUID, amount); err != nil {
+		return fmt.Errorf("capture points error: %w", err)
+	}
+
+	return insertPointsEntry(ctx, tx, userUUID, uuid.NullUUID{UUID: orderUUID, Valid: true},
+		models.PointsEntryCapture, amount, "order paid")
+}
+
+func returnPoints(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID) error {
+	const holdQuery = `
+						SELECT user_uuid, amount, status FROM "points_hold"
+							WHERE order_uuid = $1
+							FOR UPDATE
+					`
+
+	var userUUID uuid.UUID
+	var amount uint64
+	var status string
+	err := tx.QueryRowContext(ctx, holdQuery, orderUUID).Scan(&userUUID, &amount, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("select points hold error: %w", err)
+	}
+
+	var accountQuery, newStatus string
+	var entryType models.PointsEntryType
+	switch status {
+	case models.PointsHoldHeld:
+		accountQuery = `UPDATE "points_account" SET held = held - $2, updated_at = now() WHERE user_uuid = $1`
+		newStatus, entryType = models.PointsHoldReleased, models.PointsEntryRelease
+	case models.PointsHoldCaptured:
+		accountQuery = `UPDATE "points_account" SET balance = balance + $2, updated_at = now() WHERE user_uuid = $1`
+		newStatus, entryType = models.PointsHoldRefunded, models.PointsEntryRefund
+	default:
+		return nil
+	}
+
+	if _, err = tx.ExecContext(ctx, accountQuery, userUUID, amount); err != nil {
+		return fmt.Errorf("return points error: %w", err)
+	}
+
+	const statusQuery = `UPDATE "points_hold" SET status = $2, updated_at = now() WHERE order_uuid = $1`
+
+	if _, err = tx.ExecContext(ctx, statusQuery, orderUUID, newStatus); err != nil {
+		return fmt.Errorf("points hold update error: %w", err)
+	}
+
+	return insertPointsEntry(ctx, tx, userUUID, uuid.NullUUID{UUID: orderUUID, Valid: true},
+		entryType, amount, "order canceled")
+}
+
+// reducePoints возвращает amount баллов из удержания заказа, когда после
+// отмены части строк баллов списано больше новой суммы заказа: удержанные
+// баллы освобождаются, уже списанные возвращаются на баланс. Удержание,
+// возвращённое целиком, закрывается так же, как при отмене заказа.
+func reducePoints(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, amount uint64) error {
+	const holdQuery = `
+						SELECT user_uuid, amount, status FROM "points_hold"
+							WHERE order_uuid = $1
+							FOR UPDATE
+					`
+
+	var userUUID uuid.UUID
+	var held uint64
+	var status string
+	if err := tx.QueryRowContext(ctx, holdQuery, orderUUID).Scan(&userUUID, &held, &status); err != nil {
+		return fmt.Errorf("select points hold error: %w", err)
+	}
+	if amount > held {
+		return fmt.Errorf("reduce points error: %d of %d held", amount, held)
+	}
+
+	var accountQuery, closedStatus string
+	var entryType models.PointsEntryType
+	switch status {
+	case models.PointsHoldHeld:
+		accountQuery = `UPDATE "points_account" SET held = held - $2, updated_at = now() WHERE user_uuid = $1`
+		closedStatus, entryType = models.PointsHoldReleased, models.PointsEntryRelease
+	case models.PointsHoldCaptured:
+		accountQuery = `UPDATE "points_account" SET balance = balance + $2, updated_at = now() WHERE user_uuid = $1`
+		closedStatus, entryType = models.PointsHoldRefunded, models.PointsEntryRefund
+	default:
+		return fmt.Errorf("reduce points error: hold is %s", status)
+	}
+
+	if _, err := tx.ExecContext(ctx, accountQuery, userUUID, amount); err != nil {
+		return fmt.Errorf("reduce points error: %w", err)
+	}
+
+	const reduceQuery = `UPDATE "points_hold" SET amount = amount - $2, updated_at = now() WHERE order_uuid = $1`
+	const closeQuery = `UPDATE "points_hold" SET status = $2, updated_at = now() WHERE order_uuid = $1`
+
+	var err error
+	if amount == held {
+		_, err = tx.ExecContext(ctx, closeQuery, orderUUID, closedStatus)
+	} else {
+		_, err = tx.ExecContext(ctx, reduceQuery, orderUUID, amount)
+	}
+	if err != nil {
+		return fmt.Errorf("points hold update error: %w", err)
+	}
+
+	return insertPointsEntry(ctx, tx, userUUID, uuid.NullUUID{UUID: orderUUID, Valid: true},
+		entryType, amount, "order lines canceled")
+}
+
+func insertPointsEntry(
+	ctx context.Context,
+	tx *sql.Tx,
+	userUUID uuid.UUID,
+	orderUUID uuid.NullUUID,
+	entryType models.PointsEntryType,
+	amount uint64,
+	reason string,
+) error {
+	const entryQuery = `
+						INSERT INTO "points_ledger" (user_uuid, order_uuid, entry_type, amount, reason)
+							VALUES ($1, $2, $3, $4, $5)
+					`
+
+	if _, err := tx.ExecContext(ctx, entryQuery, userUUID, orderUUID, string(entryType), amount, reason); err != nil {
+		return fmt.Errorf("points ledger insert error: %w", err)
+	}
+
+	return nil
+}
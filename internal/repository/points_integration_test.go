@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func newPointsTest(t *testing.T) (*OrderRepository, *PointsRepository) {
+	t.Helper()
+
+	db := testdb.New(t)
+	testdb.Truncate(t, db,
+		"order", "order_products", "order_status_history", "outbox",
+		"points_account", "points_hold", "points_ledger",
+	)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return NewOrderRepository(log, db), NewPointsRepository(log, db)
+}
+
+func newPointsOrder(t *testing.T, userUUID uuid.UUID, points int) *models.Order {
+	t.Helper()
+
+	order := &models.Order{
+		UserUUID:    userUUID,
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Points,
+		WithPoints:  points,
+		Products:    []models.Product{{UUID: uuid.New(), Quantity: 1, UnitPrice: 1000, Currency: "RUB"}},
+	}
+	require.NoError(t, order.CalculateTotals())
+
+	return order
+}
+
+func requireBalance(t *testing.T, points *PointsRepository, userUUID uuid.UUID, balance, held uint64) {
+	t.Helper()
+
+	got, err := points.Balance(context.Background(), userUUID)
+	require.NoError(t, err)
+	require.Equal(t, balance, got.Balance)
+	require.Equal(t, held, got.Held)
+	require.Equal(t, balance-held, got.Available)
+}
+
+func TestPointsHoldCaptureRelease(t *testing.T) {
+	orders, points := newPointsTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	requireBalance(t, points, userUUID, 0, 0)
+	require.NoError(t, points.Credit(ctx, userUUID, 500, "welcome bonus"))
+
+	paid, err := orders.Create(ctx, newPointsOrder(t, userUUID, 300))
+	require.NoError(t, err)
+	requireBalance(t, points, userUUID, 500, 300)
+
+	_, err = orders.Create(ctx, newPointsOrder(t, userUUID, 300))
+	require.ErrorIs(t, err, internal_errors.ErrInsufficientPoints)
+	requireBalance(t, points, userUUID, 500, 300)
+
+	canceled, err := orders.Create(ctx, newPointsOrder(t, userUUID, 200))
+	require.NoError(t, err)
+	requireBalance(t, points, userUUID, 500, 500)
+
+	change := models.StatusChange{Actor: models.ActorSystem}
+
+	require.NoError(t, orders.MarkPaid(ctx, paid, change))
+	requireBalance(t, points, userUUID, 200, 200)
+
+	require.NoError(t, orders.Cancel(ctx, canceled, change))
+	requireBalance(t, points, userUUID, 200, 0)
+
+	// отмена оплаченного заказа возвращает списанные баллы
+	require.NoError(t, orders.Cancel(ctx, paid, change))
+	requireBalance(t, points, userUUID, 500, 0)
+}
+
+func TestPointsNoOverspend(t *testing.T) {
+	orders, points := newPointsTest(t)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	require.NoError(t, points.Credit(ctx, userUUID, 300, "welcome bonus"))
+
+	const attempts = 5
+
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := orders.Create(ctx, newPointsOrder(t, userUUID, 200))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var created int
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+
+	require.LessOrEqual(t, created, 1)
+	balance, err := points.Balance(ctx, userUUID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(created*200), balance.Held)
+	require.Equal(t, uint64(300), balance.Balance)
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// PromoRepository - справочник промокодов. Использование промокода заказом
+// учитывается в транзакциях OrderRepository, см. consumePromo и returnPromo.
+type PromoRepository struct {
+	log *slog.Logger
+	db  *sqlx.DB
+}
+
+func NewPromoRepository(log *slog.Logger, db *sqlx.DB) *PromoRepository {
+	return &PromoRepository{
+		log: log,
+		db:  db,
+	}
+}
+
+// PromoCode возвращает промокод по коду
+func (pr *PromoRepository) PromoCode(ctx context.Context, code string) (*models.PromoCode, error) {
+	const op = "repository.promo.PromoCode"
+
+	promo, err := selectPromoCode(ctx, pr.db, code)
+	if err != nil {
+		if !errors.Is(err, internal_errors.ErrPromoCodeNotFound) {
+			pr.log.Error(op, slog.String("error", err.Error()))
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return promo, nil
+}
+
+// selectPromoCode читает промокод отдельным запросом или внутри транзакции.
+// ReadAt заполняется временем базы, как и проверка срока в consumePromo, и
+// приводится к timestamp, как valid_from и valid_to, чтобы сравнение не
+// зависело от часового пояса сессии.
+func selectPromoCode(ctx context.Context, q querier, code string) (*models.PromoCode, error) {
+	const query = `
+					SELECT code, kind, percent, amount, COALESCE(currency, ''),
+							buy_quantity, get_quantity, valid_from, valid_to, per_user_limit, now()::timestamp
+						FROM "promo_code"
+						WHERE code = $1
+				`
+
+	var promo models.PromoCode
+	if err := q.QueryRowContext(ctx, query, code).Scan(
+		&promo.Code, &promo.Kind, &promo.Percent, &promo.Amount, &promo.Currency,
+		&promo.BuyQuantity, &promo.GetQuantity, &promo.ValidFrom, &promo.ValidTo, &promo.PerUserLimit,
+		&promo.ReadAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, internal_errors.ErrPromoCodeNotFound
+		}
+		return nil, fmt.Errorf("select promo code error: %w", err)
+	}
+
+	return &promo, nil
+}
+
+// CreatePromoCode заводит промокод
+func (pr *PromoRepository) CreatePromoCode(ctx context.Context, promo models.PromoCode) error {
+	const op = "repository.promo.CreatePromoCode"
+
+	const query = `
+					INSERT INTO "promo_code" (
+							code, kind, percent, amount, currency,
+							buy_quantity, get_quantity, valid_from, valid_to, per_user_limit
+						)
+						VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				`
+
+	if _, err := pr.db.ExecContext(ctx, query,
+		promo.Code, string(promo.Kind), promo.Percent, promo.Amount,
+		sql.NullString{String: promo.Currency, Valid: promo.Currency != ""},
+		promo.BuyQuantity, promo.GetQuantity, promo.ValidFrom, promo.ValidTo, promo.PerUserLimit,
+	); err != nil {
+		pr.log.Error(op, slog.String("error", err.Error()))
+		return fmt.Errorf("%s: execute statement: %w", op, err)
+	}
+
+	return nil
+}
+
+// consumePromo учитывает одно использование промокода пользователем. Лимит
+// проверяется в том же UPSERT, что и увеличение счётчика, поэтому
+// конкурентные заказы не могут превысить per_user_limit.
+func consumePromo(ctx context.Context, tx *sql.Tx, code string, userUUID uuid.UUID) error {
+	const promoQuery = `
+						SELECT per_user_limit FROM "promo_code"
+							WHERE code = $1 AND valid_from <= now() AND now() < valid_to
+					`
+
+	var limit int
+	if err := tx.QueryRowContext(ctx, promoQuery, code).Scan(&limit); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", internal_errors.ErrPromoCodeInactive, code)
+		}
+		return fmt.Errorf("select promo code error: %w", err)
+	}
+
+	const usageQuery = `
+						INSERT INTO "promo_code_usage" (code, user_uuid, used) VALUES ($1, $2, 1)
+							ON CONFLICT (code, user_uuid) DO UPDATE
+								SET used = "promo_code_usage".used + 1
+								WHERE "promo_code_usage".used < $3
+					`
+
+	result, err := tx.ExecContext(ctx, usageQuery, code, userUUID, limit)
+	if err != nil {
+		return fmt.Errorf("promo code usage error: %w", err)
+	}
+
+	used, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("promo code usage error: %w", err)
+	}
+	if used == 0 {
+		return fmt.Errorf("%w: %s", internal_errors.ErrPromoCodeLimitReached, code)
+	}
+
+	return nil
+}
+
+// returnPromo возвращает пользователю использование промокода отменённого
+// заказа. Заказ без промокода пропускается.
+func returnPromo(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID) error {
+	const usageQuery = `
+						UPDATE "promo_code_usage" u SET used = u.used - 1
+							FROM "order" o
+							WHERE o.uuid = $1 AND u.code = o.promo_code AND u.user_uuid = o.user_uuid
+								AND u.used > 0
+					`
+
+	if _, err := tx.ExecContext(ctx, usageQuery, orderUUID); err != nil {
+		return fmt.Errorf("promo code return error: %w", err)
+	}
+
+	return nil
+}
+
+// insertPromoDiscount записывает строку скидки по промокоду созданного заказа
+func insertPromoDiscount(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, code string, amount uint64) error {
+	const query = `
+					INSERT INTO "order_discount" (order_uuid, kind, promo_code, amount)
+						VALUES ($1, 'promo_code', $2, $3)
+				`
+
+	if _, err := tx.ExecContext(ctx, query, orderUUID, code, amount); err != nil {
+		return fmt.Errorf("promo discount insert error: %w", err)
+	}
+
+	return nil
+}
+
+// updatePromoDiscount переписывает сумму строки скидки по промокоду после
+// пересчёта заказа. Заказ без промокода пропускается.
+func updatePromoDiscount(ctx context.Context, tx *sql.Tx, orderUUID uuid.UUID, amount uint64) error {
+	const query = `
+					UPDATE "order_discount" SET amount = $2, updated_at = now()
+						WHERE order_uuid = $1 AND kind = 'promo_code'
+				`
+
+	if _, err := tx.ExecContext(ctx, query, orderUUID, amount); err != nil {
+		return fmt.Errorf("promo discount update error: %w", err)
+	}
+
+	return nil
+}
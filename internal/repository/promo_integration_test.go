@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internal_errors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+func newPromoTest(t *testing.T) (*OrderRepository, *PromoRepository, *sqlx.DB) {
+	t.Helper()
+
+	db := testdb.New(t)
+	testdb.Truncate(t, db,
+		"order", "order_products", "order_status_history", "outbox", "order_discount",
+		"promo_code", "promo_code_usage",
+	)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return NewOrderRepository(log, db), NewPromoRepository(log, db), db
+}
+
+func newPromoOrder(t *testing.T, promos *PromoRepository, userUUID uuid.UUID, code string) *models.Order {
+	t.Helper()
+
+	promo, err := promos.PromoCode(context.Background(), code)
+	require.NoError(t, err)
+
+	order := &models.Order{
+		UserUUID:    userUUID,
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Card,
+		Products:    []models.Product{{UUID: uuid.New(), Quantity: 3, UnitPrice: 1000, Currency: "RUB"}},
+	}
+	require.NoError(t, order.ApplyPromo(*promo, promo.ReadAt))
+
+	return order
+}
+
+func TestPromoUsageLimit(t *testing.T) {
+	orders, promos, _ := newPromoTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, promos.CreatePromoCode(ctx, models.PromoCode{
+		Code: "2PLUS1", Kind: models.PromoBuyXGetY, BuyQuantity: 2, GetQuantity: 1,
+		ValidFrom: time.Now().Add(-time.Hour), ValidTo: time.Now().Add(time.Hour), PerUserLimit: 1,
+	}))
+
+	_, err := promos.PromoCode(ctx, "UNKNOWN")
+	require.ErrorIs(t, err, internal_errors.ErrPromoCodeNotFound)
+
+	userUUID := uuid.New()
+	orderUUID, err := orders.Create(ctx, newPromoOrder(t, promos, userUUID, "2PLUS1"))
+	require.NoError(t, err)
+
+	stored, err := orders.Order(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, "2PLUS1", stored.PromoCode)
+	require.Equal(t, uint64(1000), stored.PromoDiscount)
+	require.Equal(t, uint64(2000), stored.GrandTotal)
+
+	_, err = orders.Create(ctx, newPromoOrder(t, promos, userUUID, "2PLUS1"))
+	require.ErrorIs(t, err, internal_errors.ErrPromoCodeLimitReached)
+
+	// лимит считается на пользователя
+	_, err = orders.Create(ctx, newPromoOrder(t, promos, uuid.New(), "2PLUS1"))
+	require.NoError(t, err)
+
+	// отмена заказа возвращает использование
+	require.NoError(t, orders.Cancel(ctx, orderUUID, models.StatusChange{Actor: models.ActorSystem}))
+	_, err = orders.Create(ctx, newPromoOrder(t, promos, userUUID, "2PLUS1"))
+	require.NoError(t, err)
+}
+
+func TestPromoNoOveruse(t *testing.T) {
+	orders, promos, _ := newPromoTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, promos.CreatePromoCode(ctx, models.PromoCode{
+		Code: "TEN", Kind: models.PromoPercentage, Percent: 10,
+		ValidFrom: time.Now().Add(-time.Hour), ValidTo: time.Now().Add(time.Hour), PerUserLimit: 2,
+	}))
+
+	const attempts = 5
+
+	userUUID := uuid.New()
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		order := newPromoOrder(t, promos, userUUID, "TEN")
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := orders.Create(ctx, order)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var created int
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+
+	require.LessOrEqual(t, created, 2)
+}
+
+func TestPromoDiscountLine(t *testing.T) {
+	orders, promos, db := newPromoTest(t)
+	ctx := context.Background()
+
+	require.NoError(t, promos.CreatePromoCode(ctx, models.PromoCode{
+		Code: "2PLUS1", Kind: models.PromoBuyXGetY, BuyQuantity: 2, GetQuantity: 1,
+		ValidFrom: time.Now().Add(-time.Hour), ValidTo: time.Now().Add(time.Hour), PerUserLimit: 1,
+	}))
+
+	orderUUID, err := orders.Create(ctx, newPromoOrder(t, promos, uuid.New(), "2PLUS1"))
+	require.NoError(t, err)
+
+	var line struct {
+		Kind      string `db:"kind"`
+		PromoCode string `db:"promo_code"`
+		Amount    uint64 `db:"amount"`
+	}
+	require.NoError(t, db.Get(&line,
+		`SELECT kind, promo_code, amount FROM "order_discount" WHERE order_uuid = $1`, orderUUID))
+	require.Equal(t, "promo_code", line.Kind)
+	require.Equal(t, "2PLUS1", line.PromoCode)
+	require.Equal(t, uint64(1000), line.Amount)
+
+	// строка скидки сверяется с заказом
+	_, err = db.Exec(`UPDATE "order_discount" SET amount = 1 WHERE order_uuid = $1`, orderUUID)
+	require.NoError(t, err)
+
+	mismatches, err := orders.TotalsMismatches(ctx, uuid.Nil, 10)
+	require.NoError(t, err)
+	require.Len(t, mismatches, 1)
+	require.Equal(t, uint64(1), mismatches[0].PromoDiscountLine)
+}
+
+func TestPromoValidityByDatabaseClock(t *testing.T) {
+	_, promos, db := newPromoTest(t)
+	ctx := context.Background()
+
+	var dbNow time.Time
+	require.NoError(t, db.Get(&dbNow, `SELECT now()`))
+
+	// окно закончилось по часам базы, какими бы ни были часы сервиса
+	require.NoError(t, promos.CreatePromoCode(ctx, models.PromoCode{
+		Code: "ENDED", Kind: models.PromoPercentage, Percent: 10,
+		ValidFrom: dbNow.Add(-time.Hour), ValidTo: dbNow, PerUserLimit: 1,
+	}))
+
+	promo, err := promos.PromoCode(ctx, "ENDED")
+	require.NoError(t, err)
+	require.False(t, promo.ReadAt.Before(promo.ValidTo))
+
+	order := &models.Order{
+		UserUUID:    uuid.New(),
+		Status:      models.OrderStatusCreated,
+		PaymentType: models.Card,
+		Products:    []models.Product{{UUID: uuid.New(), Quantity: 1, UnitPrice: 1000, Currency: "RUB"}},
+	}
+	require.ErrorIs(t, order.ApplyPromo(*promo, promo.ReadAt), internal_errors.ErrPromoCodeInactive)
+}
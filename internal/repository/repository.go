@@ -9,11 +9,19 @@ type Repository struct {
 	log *slog.Logger
 
 	*OrderRepository
+	*InboxRepository
+	*IdempotencyRepository
+	*PointsRepository
+	*PromoRepository
 }
 
 func NewRepository(log *slog.Logger, db *sqlx.DB) *Repository {
 	return &Repository{
-		log:             log,
-		OrderRepository: NewOrderRepository(log, db),
+		log:                   log,
+		OrderRepository:       NewOrderRepository(log, db),
+		InboxRepository:       NewInboxRepository(log, db),
+		IdempotencyRepository: NewIdempotencyRepository(log, db),
+		PointsRepository:      NewPointsRepository(log, db),
+		PromoRepository:       NewPromoRepository(log, db),
 	}
 }
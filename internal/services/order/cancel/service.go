@@ -5,90 +5,90 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/tumbleweedd/two_services_system/order_service/internal/cache_impl"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
 	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
 	"log/slog"
 )
 
-type orderGetter interface {
-	Order(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+type orderCancaler interface {
+	Cancel(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+	CancelLines(
+		ctx context.Context,
+		orderUUID uuid.UUID,
+		productUUIDs []uuid.UUID,
+		change models.StatusChange,
+	) (*models.LinesCancellation, error)
 }
 
-type orderCancaler interface {
-	Cancel(ctx context.Context, orderUUID uuid.UUID) error
+// eventPublisher - best-effort уведомления, гарантированную доставку обеспечивает outbox
+type eventPublisher interface {
+	PublishStatusEvent(ctx context.Context, status *models.StatusStruct)
 }
 
+// OrderCancellationService отменяет заказы. Переход статуса проверяет
+// репозиторий под блокировкой строки заказа: заказ в кэше мог устареть, если
+// его изменил другой процесс.
 type OrderCancellationService struct {
-	log   *slog.Logger
-	cache cache_impl.CacheI[uuid.UUID, *models.Order]
+	log *slog.Logger
 
-	orderCancaler orderCancaler
-	orderGetter   orderGetter
+	orderCancaler  orderCancaler
+	eventPublisher eventPublisher
 }
 
 func New(
 	log *slog.Logger,
-	cache cache_impl.CacheI[uuid.UUID, *models.Order],
 	orderCancaler orderCancaler,
-	orderGetter orderGetter,
+	eventPublisher eventPublisher,
 ) *OrderCancellationService {
 	return &OrderCancellationService{
-		log:           log,
-		cache:         cache,
-		orderCancaler: orderCancaler,
-		orderGetter:   orderGetter,
+		log:            log,
+		orderCancaler:  orderCancaler,
+		eventPublisher: eventPublisher,
 	}
 }
 
-func (os *OrderCancellationService) Cancel(ctx context.Context, orderUUID uuid.UUID) (err error) {
+func (os *OrderCancellationService) Cancel(
+	ctx context.Context,
+	orderUUID uuid.UUID,
+	change models.StatusChange,
+) error {
 	const op = "services.order.Cancel"
 
-	var needUpdateCache bool
-
-	order, exist := os.cache.Get(orderUUID)
-	if !exist {
-		order, err = os.orderGetter.Order(ctx, orderUUID)
-		if err != nil {
-			os.log.Error(op, slog.String("get order error", err.Error()))
+	if err := os.orderCancaler.Cancel(ctx, orderUUID, change); err != nil {
+		switch {
+		case errors.Is(err, internalErrors.ErrOrderNotFound):
+			os.log.Error(op, slog.String("order not found by uuid", err.Error()))
+			return fmt.Errorf("%s, order not found: %w", op, err)
+		case errors.Is(err, internalErrors.ErrInvalidStatusTransition):
 			return fmt.Errorf("%s: %w", op, err)
 		}
-
-		needUpdateCache = true
-	}
-
-	for _, product := range order.Products {
-		order.TotalAmount += product.Amount
+		os.log.Error(op, slog.String("cancel order error", err.Error()))
+		return fmt.Errorf("%s, cancel order: %w", op, err)
 	}
 
-	defer func() {
-		if needUpdateCache {
-			os.cache.Add(orderUUID, order)
-			os.log.InfoContext(ctx, op, "cache was updated")
-		}
-	}()
+	os.eventPublisher.PublishStatusEvent(ctx, &models.StatusStruct{OrderUUID: orderUUID, Status: models.OrderStatusCanceled})
 
-	switch order.Status {
-	case models.OrderStatusCreated, models.OrderStatusPaid:
-		if err = os.orderCancaler.Cancel(ctx, orderUUID); err != nil {
-			if errors.Is(err, internalErrors.ErrOrderNotFound) {
-				os.log.Error(op, slog.String("order not found by uuid", err.Error()))
-				return fmt.Errorf("%s, order not found: %w", op, err)
-			}
-			os.log.Error(op, slog.String("cancel order error", err.Error()))
-			return fmt.Errorf("%s, cancel order: %w", op, err)
-		}
+	return nil
+}
 
-		order.Status = models.OrderStatusCanceled
+// CancelLines отменяет строки заказа с товарами productUUIDs. Суммы заказа
+// пересчитываются в репозитории.
+func (os *OrderCancellationService) CancelLines(
+	ctx context.Context,
+	orderUUID uuid.UUID,
+	productUUIDs []uuid.UUID,
+	change models.StatusChange,
+) (*models.LinesCancellation, error) {
+	const op = "services.order.CancelLines"
+
+	cancellation, err := os.orderCancaler.CancelLines(ctx, orderUUID, productUUIDs, change)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
-		needUpdateCache = true
-	case models.OrderStatusCanceled:
-		return internalErrors.ErrOrderAlreadyCanceled
-	case models.OrderStatusDelivered:
-		return internalErrors.ErrOrderAlreadyDelivered
-	default:
-		return fmt.Errorf("order cancellation error by status %v: %w", order.Status, err)
+	if cancellation.Status == models.OrderStatusCanceled {
+		os.eventPublisher.PublishStatusEvent(ctx, &models.StatusStruct{OrderUUID: orderUUID, Status: cancellation.Status})
 	}
 
-	return
+	return cancellation, nil
 }
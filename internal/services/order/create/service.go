@@ -13,17 +13,37 @@ type orderCreator interface {
 	Create(ctx context.Context, order *models.Order) (uuid.UUID, error)
 }
 
+type promoGetter interface {
+	PromoCode(ctx context.Context, code string) (*models.PromoCode, error)
+}
+
+// eventPublisher - best-effort уведомления, гарантированную доставку обеспечивает outbox
+type eventPublisher interface {
+	PublishOrderEvent(ctx context.Context, order *models.Order)
+}
+
 type OrderCreationService struct {
 	log   *slog.Logger
 	cache cache_impl.CacheI[uuid.UUID, *models.Order]
 
-	orderCreator orderCreator
+	orderCreator   orderCreator
+	promoGetter    promoGetter
+	eventPublisher eventPublisher
 }
 
-func New(log *slog.Logger, orderCreator orderCreator) *OrderCreationService {
+func New(
+	log *slog.Logger,
+	cache cache_impl.CacheI[uuid.UUID, *models.Order],
+	orderCreator orderCreator,
+	promoGetter promoGetter,
+	eventPublisher eventPublisher,
+) *OrderCreationService {
 	return &OrderCreationService{
-		log:          log,
-		orderCreator: orderCreator,
+		log:            log,
+		cache:          cache,
+		orderCreator:   orderCreator,
+		promoGetter:    promoGetter,
+		eventPublisher: eventPublisher,
 	}
 }
 
@@ -32,12 +52,14 @@ func (os *OrderCreationService) Create(ctx context.Context, order *models.Order)
 
 	orderUUID, err := os.createOrder(ctx, order)
 	if err != nil {
-		return "", fmt.Errorf("%s: %v", op, err)
+		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	_ = os.cache.Add(orderUUID, order)
 
-	os.log.InfoContext(ctx, op, fmt.Sprint("cache was updated"))
+	os.log.InfoContext(ctx, op, slog.String("cache", "updated"))
+
+	os.eventPublisher.PublishOrderEvent(ctx, order)
 
 	return orderUUID.String(), nil
 }
@@ -45,15 +67,33 @@ func (os *OrderCreationService) Create(ctx context.Context, order *models.Order)
 func (os *OrderCreationService) createOrder(ctx context.Context, order *models.Order) (uuid.UUID, error) {
 	const op = "services.order.createOrder"
 
+	// сумма считается один раз при создании и хранится вместе с заказом
+	if err := order.CalculateTotals(); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// скидка по промокоду считается здесь, а лимит использований проверяется
+	// и расходуется в транзакции создания заказа. Срок действия в обоих местах
+	// проверяется по часам базы.
+	if order.PromoCode != "" {
+		promo, err := os.promoGetter.PromoCode(ctx, order.PromoCode)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if err = order.ApplyPromo(*promo, promo.ReadAt); err != nil {
+			return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	orderUUID, err := os.orderCreator.Create(ctx, order)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("%s: %v", op, err)
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	order.OrderUUID = orderUUID
 	for i := range order.Products {
 		order.Products[i].OrderUUID = orderUUID
-		order.TotalAmount += order.Products[i].Amount
 	}
 
 	return orderUUID, nil
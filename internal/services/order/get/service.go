@@ -3,7 +3,6 @@ package get
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/cache_impl"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
@@ -15,8 +14,15 @@ import (
 type orderGetter interface {
 	OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) (ordersMap map[uuid.UUID]models.Order, err error)
 	Order(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error)
+	StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error)
+	UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error)
+	Versions(ctx context.Context, UUIDs []uuid.UUID) (map[uuid.UUID]int, error)
 }
 
+// OrderRetrievalService читает заказы через кэш. Заказ меняют и другие
+// процессы (consumer оплат, другие реплики), поэтому кэш локального процесса
+// не знает об изменениях: перед ответом из кэша версия заказа сверяется с
+// базой, и устаревшие заказы перечитываются.
 type OrderRetrievalService struct {
 	log   *slog.Logger
 	cache cache_impl.CacheI[uuid.UUID, *models.Order]
@@ -39,7 +45,13 @@ func New(
 func (os *OrderRetrievalService) OrdersByUUIDs(ctx context.Context, UUIDs []uuid.UUID) ([]models.Order, error) {
 	const op = "service.order.OrdersByUUIDs"
 
-	result, notInCache := os.partitionOrdersByCache(ctx, UUIDs, op)
+	cached, notInCache := os.partitionOrdersByCache(ctx, UUIDs, op)
+
+	result, stale, err := os.dropStale(ctx, cached, op)
+	if err != nil {
+		return nil, err
+	}
+	notInCache = append(notInCache, stale...)
 
 	if len(notInCache) == 0 {
 		return result, nil
@@ -80,6 +92,44 @@ func (os *OrderRetrievalService) partitionOrdersByCache(ctx context.Context, UUI
 	return result, notInCache
 }
 
+// dropStale оставляет закэшированные заказы, версия которых совпадает с базой,
+// и возвращает uuid остальных, чтобы перечитать их
+func (os *OrderRetrievalService) dropStale(
+	ctx context.Context,
+	cached []models.Order,
+	op string,
+) (fresh []models.Order, stale []uuid.UUID, err error) {
+	if len(cached) == 0 {
+		return cached, nil, nil
+	}
+
+	UUIDs := make([]uuid.UUID, 0, len(cached))
+	for _, order := range cached {
+		UUIDs = append(UUIDs, order.OrderUUID)
+	}
+
+	versions, err := os.orderGetter.Versions(ctx, UUIDs)
+	if err != nil {
+		os.log.Error(op, slog.String("get versions error", err.Error()))
+		return nil, nil, err
+	}
+
+	fresh = make([]models.Order, 0, len(cached))
+	for _, order := range cached {
+		if version, ok := versions[order.OrderUUID]; ok && version == order.Version {
+			fresh = append(fresh, order)
+			continue
+		}
+		stale = append(stale, order.OrderUUID)
+	}
+
+	if len(stale) > 0 {
+		os.log.InfoContext(ctx, op, slog.Int("stale items in cache", len(stale)))
+	}
+
+	return fresh, stale, nil
+}
+
 func (os *OrderRetrievalService) checkCache(_ context.Context, orderUUID uuid.UUID,
 	wg *sync.WaitGroup, inCacheCh chan models.Order, notInCacheCh chan uuid.UUID) {
 	defer wg.Done()
@@ -128,24 +178,60 @@ func (os *OrderRetrievalService) fetchOrdersFromDB(ctx context.Context, notInCac
 		return nil, err
 	}
 
-	for orderUUID, order := range ordersMap {
-		for _, product := range order.Products {
-			order.TotalAmount += product.Amount
-		}
-		ordersMap[orderUUID] = order
-	}
-
 	return ordersMap, nil
 }
 
 func (os *OrderRetrievalService) OrderByUUID(ctx context.Context, orderUUID uuid.UUID) (*models.Order, error) {
 	const op = "service.order.Order"
 
-	order, ok := os.cache.Get(orderUUID)
-	if ok && order != nil {
-		os.log.InfoContext(ctx, op, fmt.Sprint("cache was used"))
-		return order, nil
+	if order, ok := os.cache.Get(orderUUID); ok && order != nil {
+		fresh, _, err := os.dropStale(ctx, []models.Order{*order}, op)
+		if err != nil {
+			return nil, err
+		}
+		if len(fresh) == 1 {
+			os.log.InfoContext(ctx, op, slog.String("cache", "used"))
+			return order, nil
+		}
+	}
+
+	order, err := os.orderGetter.Order(ctx, orderUUID)
+	if err != nil {
+		if !errors.Is(err, internalErrors.ErrOrderNotFound) {
+			os.log.Error(op, slog.String("get order error", err.Error()))
+		}
+		return nil, err
+	}
+
+	_ = os.cache.Add(order.OrderUUID, order)
+
+	return order, nil
+}
+
+func (os *OrderRetrievalService) StatusHistory(ctx context.Context, orderUUID uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	const op = "service.order.StatusHistory"
+
+	history, err := os.orderGetter.StatusHistory(ctx, orderUUID)
+	if err != nil {
+		if !errors.Is(err, internalErrors.ErrOrderNotFound) {
+			os.log.Error(op, slog.String("get status history error", err.Error()))
+		}
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// UserOrders возвращает страницу заказов пользователя. Выборка идёт мимо кэша:
+// кэш хранит заказы по uuid и не может ответить на запрос с фильтрами.
+func (os *OrderRetrievalService) UserOrders(ctx context.Context, filter models.OrderFilter) (*models.OrdersPage, error) {
+	const op = "service.order.UserOrders"
+
+	page, err := os.orderGetter.UserOrders(ctx, filter)
+	if err != nil {
+		os.log.Error(op, slog.String("get user orders error", err.Error()))
+		return nil, err
 	}
 
-	return os.orderGetter.Order(ctx, orderUUID)
+	return page, nil
 }
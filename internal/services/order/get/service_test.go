@@ -0,0 +1,146 @@
+package get
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// mapCache - кэш без вытеснения, сервис обращается к нему из нескольких горутин
+type mapCache struct {
+	mu     sync.Mutex
+	orders map[uuid.UUID]*models.Order
+}
+
+func (c *mapCache) Get(key uuid.UUID) (*models.Order, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.orders[key]
+	return value, ok
+}
+
+func (c *mapCache) Add(key uuid.UUID, value *models.Order) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.orders[key] = value
+	return false
+}
+
+// fakeOrders - заказы в "базе", которые меняет кто-то помимо сервиса
+type fakeOrders struct {
+	orders map[uuid.UUID]models.Order
+	reads  int
+}
+
+func (f *fakeOrders) OrdersByUUIDs(_ context.Context, UUIDs []uuid.UUID) (map[uuid.UUID]models.Order, error) {
+	f.reads++
+
+	result := make(map[uuid.UUID]models.Order)
+	for _, orderUUID := range UUIDs {
+		if order, ok := f.orders[orderUUID]; ok {
+			result[orderUUID] = order
+		}
+	}
+	if len(result) == 0 {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return result, nil
+}
+
+func (f *fakeOrders) Order(_ context.Context, orderUUID uuid.UUID) (*models.Order, error) {
+	f.reads++
+
+	order, ok := f.orders[orderUUID]
+	if !ok {
+		return nil, internalErrors.ErrOrderNotFound
+	}
+
+	return &order, nil
+}
+
+func (f *fakeOrders) StatusHistory(context.Context, uuid.UUID) ([]models.StatusHistoryEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeOrders) UserOrders(context.Context, models.OrderFilter) (*models.OrdersPage, error) {
+	return &models.OrdersPage{}, nil
+}
+
+func (f *fakeOrders) Versions(_ context.Context, UUIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	versions := make(map[uuid.UUID]int)
+	for _, orderUUID := range UUIDs {
+		if order, ok := f.orders[orderUUID]; ok {
+			versions[orderUUID] = order.Version
+		}
+	}
+
+	return versions, nil
+}
+
+func (f *fakeOrders) setStatus(orderUUID uuid.UUID, status models.OrderStatus) {
+	order := f.orders[orderUUID]
+	order.Status = status
+	order.Version++
+	f.orders[orderUUID] = order
+}
+
+func TestOrderByUUIDRereadsStaleCache(t *testing.T) {
+	orderUUID := uuid.New()
+	orders := &fakeOrders{orders: map[uuid.UUID]models.Order{
+		orderUUID: {OrderUUID: orderUUID, Status: models.OrderStatusCreated, Version: 1},
+	}}
+	svc := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &mapCache{orders: make(map[uuid.UUID]*models.Order)}, orders)
+	ctx := context.Background()
+
+	order, err := svc.OrderByUUID(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusCreated, order.Status)
+
+	_, err = svc.OrderByUUID(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, 1, orders.reads)
+
+	// заказ оплачен в другом процессе, кэш этого процесса о нём не знает
+	orders.setStatus(orderUUID, models.OrderStatusPaid)
+
+	order, err = svc.OrderByUUID(ctx, orderUUID)
+	require.NoError(t, err)
+	require.Equal(t, models.OrderStatusPaid, order.Status)
+	require.Equal(t, 2, orders.reads)
+}
+
+func TestOrdersByUUIDsRereadsStaleCache(t *testing.T) {
+	fresh, stale := uuid.New(), uuid.New()
+	orders := &fakeOrders{orders: map[uuid.UUID]models.Order{
+		fresh: {OrderUUID: fresh, Status: models.OrderStatusCreated, Version: 1},
+		stale: {OrderUUID: stale, Status: models.OrderStatusCreated, Version: 1},
+	}}
+	svc := New(slog.New(slog.NewTextHandler(io.Discard, nil)), &mapCache{orders: make(map[uuid.UUID]*models.Order)}, orders)
+	ctx := context.Background()
+
+	_, err := svc.OrdersByUUIDs(ctx, []uuid.UUID{fresh, stale})
+	require.NoError(t, err)
+
+	orders.setStatus(stale, models.OrderStatusCanceled)
+
+	result, err := svc.OrdersByUUIDs(ctx, []uuid.UUID{fresh, stale})
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+
+	statuses := make(map[uuid.UUID]models.OrderStatus)
+	for _, order := range result {
+		statuses[order.OrderUUID] = order.Status
+	}
+	require.Equal(t, models.OrderStatusCreated, statuses[fresh])
+	require.Equal(t, models.OrderStatusCanceled, statuses[stale])
+}
@@ -0,0 +1,75 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+type orderStatusChanger interface {
+	MarkPaid(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+	MarkDelivered(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error
+}
+
+// eventPublisher - best-effort уведомления, гарантированную доставку обеспечивает outbox
+type eventPublisher interface {
+	PublishStatusEvent(ctx context.Context, status *models.StatusStruct)
+}
+
+// OrderStatusService переводит заказы по статусам. Переход проверяет
+// репозиторий под блокировкой строки заказа.
+type OrderStatusService struct {
+	log *slog.Logger
+
+	orderStatusChanger orderStatusChanger
+	eventPublisher     eventPublisher
+}
+
+func New(
+	log *slog.Logger,
+	orderStatusChanger orderStatusChanger,
+	eventPublisher eventPublisher,
+) *OrderStatusService {
+	return &OrderStatusService{
+		log:                log,
+		orderStatusChanger: orderStatusChanger,
+		eventPublisher:     eventPublisher,
+	}
+}
+
+func (os *OrderStatusService) MarkPaid(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "services.order.MarkPaid"
+
+	return os.transition(ctx, op, orderUUID, models.OrderStatusPaid, change, os.orderStatusChanger.MarkPaid)
+}
+
+func (os *OrderStatusService) MarkDelivered(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error {
+	const op = "services.order.MarkDelivered"
+
+	return os.transition(ctx, op, orderUUID, models.OrderStatusDelivered, change, os.orderStatusChanger.MarkDelivered)
+}
+
+func (os *OrderStatusService) transition(
+	ctx context.Context,
+	op string,
+	orderUUID uuid.UUID,
+	to models.OrderStatus,
+	change models.StatusChange,
+	apply func(ctx context.Context, orderUUID uuid.UUID, change models.StatusChange) error,
+) error {
+	if err := apply(ctx, orderUUID, change); err != nil {
+		if !errors.Is(err, internalErrors.ErrInvalidStatusTransition) && !errors.Is(err, internalErrors.ErrOrderNotFound) {
+			os.log.Error(op, slog.String("change status error", err.Error()))
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	os.eventPublisher.PublishStatusEvent(ctx, &models.StatusStruct{OrderUUID: orderUUID, Status: to})
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package status
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	internalErrors "github.com/tumbleweedd/two_services_system/order_service/internal/lib/errors"
+)
+
+// fakeOrders хранит статусы заказов и проверяет переходы, как репозиторий
+type fakeOrders struct {
+	statuses map[uuid.UUID]models.OrderStatus
+}
+
+func (f *fakeOrders) MarkPaid(_ context.Context, orderUUID uuid.UUID, _ models.StatusChange) error {
+	return f.change(orderUUID, models.OrderStatusPaid)
+}
+
+func (f *fakeOrders) MarkDelivered(_ context.Context, orderUUID uuid.UUID, _ models.StatusChange) error {
+	return f.change(orderUUID, models.OrderStatusDelivered)
+}
+
+func (f *fakeOrders) change(orderUUID uuid.UUID, to models.OrderStatus) error {
+	from, ok := f.statuses[orderUUID]
+	if !ok {
+		return internalErrors.ErrOrderNotFound
+	}
+
+	if err := from.ValidateTransition(to); err != nil {
+		return err
+	}
+	f.statuses[orderUUID] = to
+
+	return nil
+}
+
+type fakePublisher struct {
+	events []models.StatusStruct
+}
+
+func (f *fakePublisher) PublishStatusEvent(_ context.Context, status *models.StatusStruct) {
+	f.events = append(f.events, *status)
+}
+
+func TestMarkPaidMarkDelivered(t *testing.T) {
+	created, canceled := uuid.New(), uuid.New()
+	orders := &fakeOrders{statuses: map[uuid.UUID]models.OrderStatus{
+		created:  models.OrderStatusCreated,
+		canceled: models.OrderStatusCanceled,
+	}}
+	publisher := &fakePublisher{}
+	service := New(slog.New(slog.NewTextHandler(io.Discard, nil)), orders, publisher)
+
+	ctx := context.Background()
+	change := models.StatusChange{Actor: models.ActorSystem}
+
+	// доставить можно только оплаченный заказ
+	err := service.MarkDelivered(ctx, created, change)
+	require.ErrorIs(t, err, internalErrors.ErrInvalidStatusTransition)
+	require.ErrorIs(t, err, internalErrors.ErrOrderNotPaid)
+
+	require.NoError(t, service.MarkPaid(ctx, created, change))
+	require.ErrorIs(t, service.MarkPaid(ctx, created, change), internalErrors.ErrOrderAlreadyPaid)
+	require.NoError(t, service.MarkDelivered(ctx, created, change))
+	require.ErrorIs(t, service.MarkDelivered(ctx, created, change), internalErrors.ErrOrderAlreadyDelivered)
+
+	require.ErrorIs(t, service.MarkPaid(ctx, canceled, change), internalErrors.ErrOrderAlreadyCanceled)
+	require.ErrorIs(t, service.MarkPaid(ctx, uuid.New(), change), internalErrors.ErrOrderNotFound)
+
+	// событие публикуется только после успешного перехода
+	require.Equal(t, []models.StatusStruct{
+		{OrderUUID: created, Status: models.OrderStatusPaid},
+		{OrderUUID: created, Status: models.OrderStatusDelivered},
+	}, publisher.events)
+	require.Equal(t, models.OrderStatusDelivered, orders.statuses[created])
+	require.Equal(t, models.OrderStatusCanceled, orders.statuses[canceled])
+}
@@ -0,0 +1,63 @@
+package totals
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+const defaultBatchSize = 500
+
+type mismatchFinder interface {
+	TotalsMismatches(ctx context.Context, after uuid.UUID, limit int) ([]models.TotalsMismatch, error)
+}
+
+// ConsistencyChecker ищет заказы, хранимые суммы которых не сходятся со строками.
+// Заказы просматриваются пачками по uuid, поэтому проверка не держит одну
+// длинную транзакцию на всю таблицу.
+type ConsistencyChecker struct {
+	log       *slog.Logger
+	finder    mismatchFinder
+	batchSize int
+}
+
+func New(log *slog.Logger, finder mismatchFinder, batchSize int) *ConsistencyChecker {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &ConsistencyChecker{
+		log:       log,
+		finder:    finder,
+		batchSize: batchSize,
+	}
+}
+
+// Check передаёт каждый найденный заказ в report и возвращает их количество
+func (c *ConsistencyChecker) Check(ctx context.Context, report func(models.TotalsMismatch)) (int, error) {
+	const op = "services.order.totals.Check"
+
+	var found int
+	after := uuid.Nil
+	for {
+		mismatches, err := c.finder.TotalsMismatches(ctx, after, c.batchSize)
+		if err != nil {
+			return found, fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, mismatch := range mismatches {
+			report(mismatch)
+		}
+		found += len(mismatches)
+
+		if len(mismatches) < c.batchSize {
+			c.log.InfoContext(ctx, op, slog.Int("mismatches", found))
+			return found, nil
+		}
+
+		after = mismatches[len(mismatches)-1].OrderUUID
+	}
+}
@@ -0,0 +1,41 @@
+package points
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+)
+
+type balanceGetter interface {
+	Balance(ctx context.Context, userUUID uuid.UUID) (*models.PointsBalance, error)
+}
+
+// PointsService отдаёт баланс баллов лояльности. Баллы удерживаются, списываются
+// и возвращаются в транзакциях заказа, а не через этот сервис.
+type PointsService struct {
+	log *slog.Logger
+
+	balanceGetter balanceGetter
+}
+
+func New(log *slog.Logger, balanceGetter balanceGetter) *PointsService {
+	return &PointsService{
+		log:           log,
+		balanceGetter: balanceGetter,
+	}
+}
+
+func (ps *PointsService) Balance(ctx context.Context, userUUID uuid.UUID) (*models.PointsBalance, error) {
+	const op = "services.points.Balance"
+
+	balance, err := ps.balanceGetter.Balance(ctx, userUUID)
+	if err != nil {
+		ps.log.Error(op, slog.String("get balance error", err.Error()))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return balance, nil
+}
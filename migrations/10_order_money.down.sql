@@ -0,0 +1,11 @@
+ALTER TABLE "order"
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS total_amount,
+    DROP COLUMN IF EXISTS with_points;
+
+ALTER TABLE "order_products"
+    DROP COLUMN IF EXISTS quantity,
+    DROP COLUMN IF EXISTS currency,
+    DROP CONSTRAINT IF EXISTS chk_order_products_unit_price;
+ALTER TABLE "order_products" ALTER COLUMN unit_price TYPE int;
+ALTER TABLE "order_products" RENAME COLUMN unit_price TO amount;
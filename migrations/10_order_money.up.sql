@@ -0,0 +1,28 @@
+-- строка заказа: количество, цена за единицу в минорных единицах и валюта ISO 4217.
+-- Прежнее amount было ценой строки, поэтому оно становится ценой за единицу
+-- при количестве 1. Валюта до этой миграции не хранилась, старые заказы - в рублях.
+ALTER TABLE "order_products" RENAME COLUMN amount TO unit_price;
+ALTER TABLE "order_products"
+    ALTER COLUMN unit_price TYPE bigint,
+    ADD CONSTRAINT chk_order_products_unit_price CHECK (unit_price >= 0),
+    ADD COLUMN IF NOT EXISTS quantity int     NOT NULL DEFAULT 1 CONSTRAINT chk_order_products_quantity CHECK (quantity > 0),
+    ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE "order_products"
+    ALTER COLUMN quantity DROP DEFAULT,
+    ALTER COLUMN currency DROP DEFAULT;
+
+-- сумма заказа считается при создании и хранится вместе с оплатой баллами
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS currency     char(3),
+    ADD COLUMN IF NOT EXISTS total_amount bigint NOT NULL DEFAULT 0 CONSTRAINT chk_order_total_amount CHECK (total_amount >= 0),
+    ADD COLUMN IF NOT EXISTS with_points  bigint NOT NULL DEFAULT 0 CONSTRAINT chk_order_with_points CHECK (with_points >= 0);
+
+UPDATE "order" o
+SET currency     = 'RUB',
+    total_amount = COALESCE((SELECT sum(op.unit_price * op.quantity)
+                             FROM "order_products" op
+                             WHERE op.order_uuid = o.uuid), 0);
+
+ALTER TABLE "order"
+    ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN total_amount DROP DEFAULT;
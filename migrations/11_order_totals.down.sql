@@ -0,0 +1,3 @@
+ALTER TABLE "order"
+    DROP COLUMN IF EXISTS points_discount,
+    DROP COLUMN IF EXISTS grand_total;
@@ -0,0 +1,17 @@
+-- total_amount - сумма строк (subtotal), points_discount - списанные баллы,
+-- grand_total - сумма к оплате. Баллы списываются только при оплате баллами
+-- (payment_type = 2), см. models.Order.CalculateTotals.
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS points_discount bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS grand_total     bigint;
+
+UPDATE "order"
+SET points_discount = CASE WHEN payment_type = 2 THEN LEAST(with_points, total_amount) ELSE 0 END;
+UPDATE "order"
+SET grand_total = total_amount - points_discount;
+
+ALTER TABLE "order"
+    ALTER COLUMN points_discount DROP DEFAULT,
+    ALTER COLUMN grand_total SET NOT NULL,
+    ADD CONSTRAINT chk_order_points_discount CHECK (points_discount >= 0 AND points_discount <= total_amount),
+    ADD CONSTRAINT chk_order_grand_total CHECK (grand_total >= 0);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "points_ledger";
+DROP TABLE IF EXISTS "points_hold";
+DROP TABLE IF EXISTS "points_account";
@@ -0,0 +1,42 @@
+-- баланс баллов пользователя: held - баллы, удержанные под неоплаченные заказы.
+-- Доступно balance - held, ограничения не дают удержать или списать больше.
+CREATE TABLE IF NOT EXISTS "points_account"
+(
+    user_uuid  uuid PRIMARY KEY,
+    balance    bigint    NOT NULL DEFAULT 0,
+    held       bigint    NOT NULL DEFAULT 0,
+    updated_at timestamp NOT NULL DEFAULT now(),
+
+    CONSTRAINT chk_points_account_balance CHECK (balance >= 0),
+    CONSTRAINT chk_points_account_held CHECK (held >= 0 AND held <= balance)
+);
+
+-- удержание баллов под заказ: held -> captured при оплате,
+-- held -> released или captured -> refunded при отмене
+CREATE TABLE IF NOT EXISTS "points_hold"
+(
+    order_uuid uuid PRIMARY KEY,
+    user_uuid  uuid      NOT NULL,
+    amount     bigint    NOT NULL,
+    status     text      NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_points_hold_order FOREIGN KEY (order_uuid) REFERENCES "order" (uuid),
+    CONSTRAINT chk_points_hold_amount CHECK (amount > 0)
+);
+
+-- журнал движений баллов, строки только добавляются
+CREATE TABLE IF NOT EXISTS "points_ledger"
+(
+    id         bigserial PRIMARY KEY,
+    user_uuid  uuid      NOT NULL,
+    order_uuid uuid,
+    entry_type text      NOT NULL,
+    amount     bigint    NOT NULL,
+    reason     text      NOT NULL DEFAULT '',
+    created_at timestamp NOT NULL DEFAULT now(),
+
+    CONSTRAINT chk_points_ledger_amount CHECK (amount > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_points_ledger_user_uuid ON "points_ledger" (user_uuid, id);
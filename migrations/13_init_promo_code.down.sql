@@ -0,0 +1,8 @@
+ALTER TABLE "order"
+    DROP CONSTRAINT IF EXISTS chk_order_discounts,
+    DROP COLUMN IF EXISTS promo_code,
+    DROP COLUMN IF EXISTS promo_discount,
+    ADD CONSTRAINT chk_order_points_discount CHECK (points_discount >= 0 AND points_discount <= total_amount);
+
+DROP TABLE IF EXISTS "promo_code_usage";
+DROP TABLE IF EXISTS "promo_code";
@@ -0,0 +1,48 @@
+-- промокоды: percentage - процент от суммы строк, fixed - фиксированная сумма
+-- в валюте currency, buy_x_get_y - из каждых buy_quantity + get_quantity единиц
+-- строки get_quantity бесплатно. Код действует в [valid_from, valid_to).
+CREATE TABLE IF NOT EXISTS "promo_code"
+(
+    code           text PRIMARY KEY,
+    kind           text      NOT NULL,
+    percent        int       NOT NULL DEFAULT 0,
+    amount         bigint    NOT NULL DEFAULT 0,
+    currency       char(3),
+    buy_quantity   int       NOT NULL DEFAULT 0,
+    get_quantity   int       NOT NULL DEFAULT 0,
+    valid_from     timestamp NOT NULL,
+    valid_to       timestamp NOT NULL,
+    per_user_limit int       NOT NULL DEFAULT 1,
+    created_at     timestamp NOT NULL DEFAULT now(),
+
+    CONSTRAINT chk_promo_code_kind CHECK (
+        (kind = 'percentage' AND percent BETWEEN 1 AND 100)
+            OR (kind = 'fixed' AND amount > 0 AND currency IS NOT NULL)
+            OR (kind = 'buy_x_get_y' AND buy_quantity > 0 AND get_quantity > 0)
+        ),
+    CONSTRAINT chk_promo_code_validity CHECK (valid_from < valid_to),
+    CONSTRAINT chk_promo_code_per_user_limit CHECK (per_user_limit > 0)
+);
+
+-- сколько раз пользователь применил код; отмена заказа возвращает использование
+CREATE TABLE IF NOT EXISTS "promo_code_usage"
+(
+    code      text NOT NULL,
+    user_uuid uuid NOT NULL,
+    used      int  NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (code, user_uuid),
+    CONSTRAINT fk_promo_code_usage_code FOREIGN KEY (code) REFERENCES "promo_code" (code),
+    CONSTRAINT chk_promo_code_usage_used CHECK (used >= 0)
+);
+
+-- скидка по промокоду - отдельная строка итогов заказа:
+-- grand_total = total_amount - promo_discount - points_discount
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS promo_code     text,
+    ADD COLUMN IF NOT EXISTS promo_discount bigint NOT NULL DEFAULT 0,
+    ADD CONSTRAINT fk_order_promo_code FOREIGN KEY (promo_code) REFERENCES "promo_code" (code),
+    DROP CONSTRAINT IF EXISTS chk_order_points_discount,
+    ADD CONSTRAINT chk_order_discounts CHECK (
+        points_discount >= 0 AND promo_discount >= 0 AND points_discount + promo_discount <= total_amount
+        );
@@ -0,0 +1,3 @@
+-- отменённые строки заказа остаются в таблице, но не входят в суммы заказа
+ALTER TABLE "order_products"
+    ADD COLUMN IF NOT EXISTS canceled_at timestamp;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_outbox_dead_letter_order_uuid;
@@ -0,0 +1,2 @@
+-- релей не выбирает события заказов, у которых есть событие в outbox_dead_letter, см. OutboxProducer.claimEvents
+CREATE INDEX IF NOT EXISTS idx_outbox_dead_letter_order_uuid ON "outbox_dead_letter" (order_uuid);
@@ -0,0 +1,24 @@
+-- строки скидок заказа. Скидка по промокоду - строка с kind = 'promo_code',
+-- её amount всегда равен order.promo_discount
+CREATE TABLE IF NOT EXISTS "order_discount"
+(
+    id         bigserial PRIMARY KEY,
+    order_uuid uuid      NOT NULL,
+    kind       text      NOT NULL,
+    promo_code text,
+    amount     bigint    NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_order_discount_order FOREIGN KEY (order_uuid) REFERENCES "order" (uuid),
+    CONSTRAINT fk_order_discount_promo_code FOREIGN KEY (promo_code) REFERENCES "promo_code" (code),
+    CONSTRAINT chk_order_discount_kind CHECK (kind = 'promo_code' AND promo_code IS NOT NULL),
+    CONSTRAINT chk_order_discount_amount CHECK (amount >= 0),
+    CONSTRAINT uq_order_discount_kind UNIQUE (order_uuid, kind)
+);
+
+INSERT INTO "order_discount" (order_uuid, kind, promo_code, amount)
+SELECT uuid, 'promo_code', promo_code, promo_discount
+FROM "order"
+WHERE promo_code IS NOT NULL
+ON CONFLICT DO NOTHING;
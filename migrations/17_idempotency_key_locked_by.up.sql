@@ -0,0 +1,3 @@
+-- запрос, занявший ключ: Complete и Release меняют строку, только пока ключ
+-- не перехватил другой запрос после lock_timeout
+ALTER TABLE "idempotency_key" ADD COLUMN IF NOT EXISTS locked_by uuid;
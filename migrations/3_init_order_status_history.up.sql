@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS "order_status_history"
+(
+    id          bigserial PRIMARY KEY,
+    order_uuid  uuid NOT NULL,
+    from_status int,
+    to_status   int  NOT NULL,
+    actor       text NOT NULL DEFAULT '',
+    reason      text NOT NULL DEFAULT '',
+    changed_at  timestamp     DEFAULT now(),
+
+    CONSTRAINT fk_order_status_history_order_uuid FOREIGN KEY (order_uuid) REFERENCES "order" (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_uuid ON "order_status_history" (order_uuid, id);
+
+-- у заказов, созданных до появления истории, остаётся хотя бы текущий статус
+INSERT INTO "order_status_history" (order_uuid, to_status, actor, reason, changed_at)
+SELECT uuid, status, 'migration', 'history backfill', updated_at
+FROM "order";
@@ -0,0 +1,8 @@
+ALTER TABLE "outbox"
+    DROP COLUMN IF EXISTS event_type,
+    DROP COLUMN IF EXISTS payload,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS aggregate_version;
+
+ALTER TABLE "order"
+    DROP COLUMN IF EXISTS version;
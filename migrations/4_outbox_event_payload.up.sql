@@ -0,0 +1,8 @@
+ALTER TABLE "order"
+    ADD COLUMN IF NOT EXISTS version int NOT NULL DEFAULT 1;
+
+ALTER TABLE "outbox"
+    ADD COLUMN IF NOT EXISTS event_type        text      NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS payload           jsonb     NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS created_at        timestamp NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS aggregate_version int       NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "outbox_dead_letter";
+
+ALTER TABLE "outbox"
+    DROP COLUMN IF EXISTS attempts,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS next_attempt_at;
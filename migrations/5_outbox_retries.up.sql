@@ -0,0 +1,18 @@
+ALTER TABLE "outbox"
+    ADD COLUMN IF NOT EXISTS attempts        int       NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS last_error      text,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamp NOT NULL DEFAULT now();
+
+CREATE TABLE IF NOT EXISTS "outbox_dead_letter"
+(
+    id                bigserial PRIMARY KEY,
+    event_uuid        uuid      NOT NULL,
+    order_uuid        uuid      NOT NULL,
+    event_type        text      NOT NULL,
+    payload           jsonb     NOT NULL,
+    created_at        timestamp NOT NULL,
+    aggregate_version int       NOT NULL,
+    attempts          int       NOT NULL,
+    last_error        text,
+    dead_at           timestamp NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "outbox_archive";
+
+DROP INDEX IF EXISTS idx_outbox_sent_at;
+DROP INDEX IF EXISTS idx_outbox_unsent;
+
+ALTER TABLE "outbox"
+    DROP COLUMN IF EXISTS sent_at;
@@ -0,0 +1,22 @@
+ALTER TABLE "outbox"
+    ADD COLUMN IF NOT EXISTS sent_at timestamp;
+
+UPDATE "outbox" SET sent_at = created_at WHERE send = TRUE AND sent_at IS NULL;
+
+-- релей ищет только неотправленные строки, отправленные копятся до очистки
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON "outbox" (id) WHERE send = FALSE;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON "outbox" (sent_at) WHERE send = TRUE;
+
+CREATE TABLE IF NOT EXISTS "outbox_archive"
+(
+    id                bigint PRIMARY KEY,
+    event_uuid        uuid      NOT NULL,
+    order_uuid        uuid      NOT NULL,
+    event_type        text      NOT NULL,
+    payload           jsonb     NOT NULL,
+    created_at        timestamp NOT NULL,
+    aggregate_version int       NOT NULL,
+    attempts          int       NOT NULL,
+    sent_at           timestamp,
+    archived_at       timestamp NOT NULL DEFAULT now()
+);
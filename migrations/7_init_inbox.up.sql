@@ -0,0 +1,10 @@
+-- входящие события, уже обработанные консьюмерами: одно и то же событие
+-- может прийти несколько раз, потому что доставка at-least-once
+CREATE TABLE IF NOT EXISTS "inbox"
+(
+    consumer     text      NOT NULL,
+    event_uuid   uuid      NOT NULL,
+    processed_at timestamp NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (consumer, event_uuid)
+);
@@ -0,0 +1,13 @@
+-- ключи идемпотентности POST /order: повтор запроса с тем же ключом
+-- возвращает сохранённый ответ вместо создания нового заказа
+CREATE TABLE IF NOT EXISTS "idempotency_key"
+(
+    key          text PRIMARY KEY,
+    request_hash text      NOT NULL,
+    -- status_code и response пусты, пока первый запрос ещё выполняется
+    status_code  int,
+    response     bytea,
+    created_at   timestamp NOT NULL DEFAULT now(),
+    expires_at   timestamp NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_key_expires_at ON "idempotency_key" (expires_at);
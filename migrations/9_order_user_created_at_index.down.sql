@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_order_user_uuid_created_at;
@@ -0,0 +1,2 @@
+-- выборка заказов пользователя постранично по (created_at, uuid), см. OrderRepository.UserOrders
+CREATE INDEX IF NOT EXISTS idx_order_user_uuid_created_at ON "order" (user_uuid, created_at);
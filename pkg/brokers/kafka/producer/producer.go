@@ -4,39 +4,64 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rcrowley/go-metrics"
 	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
-	"log/slog"
 )
 
+// Имена счётчиков в реестре метрик sarama, к ним добавляется "-for-topic-<topic>"
+const (
+	metricSent    = "notification-sent"
+	metricFailed  = "notification-failed"
+	metricDropped = "notification-dropped"
+)
+
+// messageEncoder возвращает значение сообщения и заголовки конверта события
+type messageEncoder interface {
+	Encode(event models.OutboxEvent) ([]byte, []sarama.RecordHeader, error)
+}
+
+// Producer - канал быстрых уведомлений о заказах рядом с outbox.
+//
+// Доставка best-effort: сообщение уходит сразу после коммита изменения, не
+// дожидаясь релея, но может потеряться. Гарантированную доставку по-прежнему
+// обеспечивает outbox, поэтому потребители уведомлений должны уметь получить
+// то же событие повторно из основного топика.
+//
+// Выполнение дальнейших действий в сервисе заказов не зависит от успешной
+// обработки уведомления брокером, поэтому используется sarama.AsyncProducer.
+// Ошибки отправки пишутся в лог с ключом сообщения и считаются в MetricRegistry.
 type Producer struct {
-	log *slog.Logger
+	log     *slog.Logger
+	encoder messageEncoder
 
 	orderEventTopic  string
 	statusEventTopic string
 
-	orderEventsChan       chan models.Event
-	changeStatusEventChan chan models.Event
-	done                  chan struct{}
-
-	// выполнение дальнейших действий в сервисе заказов не зависит от успешной
-	// обработки заказа брокером, поэтому использую sarama.AsyncProducer
 	producer sarama.AsyncProducer
+	registry metrics.Registry
+
+	// mu защищает Input() от записи после начала закрытия продюсера
+	mu     sync.RWMutex
+	closed bool
+
+	// drained закрывается, когда прочитаны все результаты отправки
+	drained chan struct{}
 }
 
 func NewProducer(
-	ctx context.Context,
 	log *slog.Logger,
+	encoder messageEncoder,
 	orderEventTopic string,
 	statusEventTopic string,
-	orderEventsChan chan models.Event,
-	changeStatusEventChan chan models.Event,
-	done chan struct{},
 	brokerAddress []string,
 ) (*Producer, error) {
 	producerConfig := sarama.NewConfig()
-	//config.Producer.Idempotent = true
-	//config.Net.MaxOpenRequests = 1
 	producerConfig.Producer.RequiredAcks = sarama.WaitForLocal
 	producerConfig.Producer.Compression = sarama.CompressionNone
 	producerConfig.Producer.Return.Successes = true
@@ -47,98 +72,200 @@ func NewProducer(
 		return nil, err
 	}
 
-	go func() {
-		for {
-			select {
-			case sendErr, ok := <-producer.Errors():
-				if !ok {
-					return
-				}
-
-				log.Warn("failed to send message", slog.String("error", sendErr.Error()))
-			case success, ok := <-producer.Successes():
-				if !ok {
-					return
-				}
-
-				log.Debug("successfully sent message", slog.String("topic", success.Topic))
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	return &Producer{
-		log:                   log,
-		orderEventsChan:       orderEventsChan,
-		changeStatusEventChan: changeStatusEventChan,
-		producer:              producer,
-		orderEventTopic:       orderEventTopic,
-		statusEventTopic:      statusEventTopic,
-		done:                  done,
-	}, nil
+	return newProducer(log, encoder, orderEventTopic, statusEventTopic, producer, producerConfig.MetricRegistry), nil
 }
 
-func (p *Producer) ProduceOrderEvent(ctx context.Context) {
-	const op = "brokers.kafka.producer.ProduceOrderEvent"
+func newProducer(
+	log *slog.Logger,
+	encoder messageEncoder,
+	orderEventTopic string,
+	statusEventTopic string,
+	producer sarama.AsyncProducer,
+	registry metrics.Registry,
+) *Producer {
+	p := &Producer{
+		log:              log,
+		encoder:          encoder,
+		orderEventTopic:  orderEventTopic,
+		statusEventTopic: statusEventTopic,
+		producer:         producer,
+		registry:         registry,
+		drained:          make(chan struct{}),
+	}
+
+	go p.handleResults()
 
-	p.eventProcessing(ctx, op, p.orderEventTopic)
+	return p
 }
 
-func (p *Producer) ProduceStatusEvent(ctx context.Context) {
-	const op = "brokers.kafka.producer.ProduceStatusEvent"
+// MetricRegistry возвращает реестр, в котором лежат метрики sarama и счётчики уведомлений
+func (p *Producer) MetricRegistry() metrics.Registry {
+	return p.registry
+}
+
+// PublishOrderEvent отправляет уведомление о создании заказа
+func (p *Producer) PublishOrderEvent(ctx context.Context, order *models.Order) {
+	const op = "brokers.kafka.producer.PublishOrderEvent"
+
+	p.publish(ctx, op, p.orderEventTopic, models.EventTypeOrderCreated, order)
+}
+
+// PublishStatusEvent отправляет уведомление о смене статуса заказа
+func (p *Producer) PublishStatusEvent(ctx context.Context, status *models.StatusStruct) {
+	const op = "brokers.kafka.producer.PublishStatusEvent"
 
-	p.eventProcessing(ctx, op, p.statusEventTopic)
+	p.publish(ctx, op, p.statusEventTopic, models.EventTypeByStatus(status.Status), status)
 }
 
-func (p *Producer) eventProcessing(ctx context.Context, op string, topic string) {
-	var eventChan chan models.Event
-	switch topic {
-	case p.orderEventTopic:
-		eventChan = p.orderEventsChan
-	case p.statusEventTopic:
-		eventChan = p.changeStatusEventChan
+// publish не блокирует вызывающего: если буфер продюсера заполнен или
+// продюсер уже закрывается, уведомление отбрасывается
+func (p *Producer) publish(ctx context.Context, op string, topic string, eventType models.EventType, event models.Event) {
+	log := p.log.With(slog.String("topic", topic), slog.String("key", event.UUID()))
+
+	message, err := p.producerMessage(topic, eventType, event)
+	if err != nil {
+		log.ErrorContext(ctx, op, slog.String("failed to encode event", err.Error()))
+		p.counter(metricFailed, topic).Inc(1)
+		return
 	}
 
-ProducerLoop:
-	for {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		log.WarnContext(ctx, op, slog.String("drop", "producer is closed"))
+		p.counter(metricDropped, topic).Inc(1)
+		return
+	}
+
+	select {
+	case p.producer.Input() <- message:
+		log.DebugContext(ctx, op, slog.String("status", "enqueued"))
+	default:
+		log.WarnContext(ctx, op, slog.String("drop", "producer buffer is full"))
+		p.counter(metricDropped, topic).Inc(1)
+	}
+}
+
+// producerMessage кодирует уведомление тем же кодировщиком, что и события outbox.
+// У уведомления нет строки в outbox, поэтому event_uuid генерируется здесь, а
+// версия агрегата не заполняется.
+func (p *Producer) producerMessage(topic string, eventType models.EventType, event models.Event) (*sarama.ProducerMessage, error) {
+	orderUUID, err := uuid.Parse(event.UUID())
+	if err != nil {
+		return nil, fmt.Errorf("parse order uuid: %w", err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("marshal payload: %w", err)
+	}
+
+	bytes, headers, err := p.encoder.Encode(models.OutboxEvent{
+		EventUUID: uuid.New(),
+		EventType: eventType,
+		OrderUUID: orderUUID,
+		CreatedAt: time.Now().UTC(),
+		Payload:   payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sarama.ProducerMessage{
+		Topic:   topic,
+		Key:     sarama.StringEncoder(event.UUID()),
+		Value:   sarama.ByteEncoder(bytes),
+		Headers: headers,
+	}, nil
+}
+
+// handleResults читает Successes и Errors, пока sarama не закроет оба канала
+func (p *Producer) handleResults() {
+	const op = "brokers.kafka.producer.handleResults"
+
+	defer close(p.drained)
+
+	successes, errs := p.producer.Successes(), p.producer.Errors()
+	for successes != nil || errs != nil {
 		select {
-		case event, ok := <-eventChan:
+		case msg, ok := <-successes:
 			if !ok {
-				break ProducerLoop
-			}
-
-			p.log.Debug(op, fmt.Sprintf("send %s #%s to kafka", topic, event.UUID()))
-			bytes, err := json.Marshal(event)
-			if err != nil {
-				p.log.Error(op, slog.String("failed to marshal order", err.Error()))
+				successes = nil
 				continue
 			}
 
-			message := &sarama.ProducerMessage{
-				Topic: topic,
-				Key:   sarama.StringEncoder(event.UUID()),
-				Value: sarama.ByteEncoder(bytes),
+			p.counter(metricSent, msg.Topic).Inc(1)
+			p.log.Debug(op,
+				slog.String("topic", msg.Topic),
+				slog.String("key", messageKey(msg)),
+				slog.Int("partition", int(msg.Partition)),
+				slog.Int64("offset", msg.Offset),
+			)
+		case sendErr, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
 			}
 
-			p.producer.Input() <- message
-		case <-ctx.Done():
-			break ProducerLoop
+			p.counter(metricFailed, sendErr.Msg.Topic).Inc(1)
+			p.log.Error(op,
+				slog.String("topic", sendErr.Msg.Topic),
+				slog.String("key", messageKey(sendErr.Msg)),
+				slog.String("error", sendErr.Err.Error()),
+			)
 		}
 	}
 }
 
-func (p *Producer) Close() error {
-	close(p.producer.Input())
+func (p *Producer) counter(name, topic string) metrics.Counter {
+	return metrics.GetOrRegisterCounter(name+"-for-topic-"+topic, p.registry)
+}
 
-	err := p.producer.Close()
-	if err != nil {
-		return err
+// Close перестаёт принимать уведомления и ждёт, пока все сообщения в полёте
+// будут доставлены брокеру или вернутся с ошибкой
+func (p *Producer) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
 	}
+	p.closed = true
+	p.mu.Unlock()
 
-	close(p.done)
-	close(p.orderEventsChan)
-	close(p.changeStatusEventChan)
+	// AsyncClose сам закрывает Input() и дожидается отправки буфера, после
+	// чего закрывает Successes() и Errors()
+	p.producer.AsyncClose()
+	<-p.drained
 
 	return nil
 }
+
+// NopProducer используется, когда уведомления выключены в конфиге
+type NopProducer struct{}
+
+func (NopProducer) PublishOrderEvent(context.Context, *models.Order) {}
+
+func (NopProducer) PublishStatusEvent(context.Context, *models.StatusStruct) {}
+
+func (NopProducer) Close() error {
+	return nil
+}
+
+// MetricRegistry возвращает пустой реестр: выключенные уведомления ничего не считают
+func (NopProducer) MetricRegistry() metrics.Registry {
+	return metrics.NewRegistry()
+}
+
+func messageKey(msg *sarama.ProducerMessage) string {
+	if msg == nil || msg.Key == nil {
+		return ""
+	}
+
+	key, err := msg.Key.Encode()
+	if err != nil {
+		return ""
+	}
+
+	return string(key)
+}
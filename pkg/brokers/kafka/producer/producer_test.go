@@ -0,0 +1,84 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/google/uuid"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/domain/models"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/encoding"
+)
+
+const (
+	testOrderTopic  = "order_topic"
+	testStatusTopic = "status_topic"
+)
+
+func newTestProducer(t *testing.T) (*Producer, *mocks.AsyncProducer) {
+	t.Helper()
+
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+
+	encoder, err := encoding.NewCloudEvents(encoding.NewJSONEncoder(), "/order_service", encoding.ModeStructured)
+	require.NoError(t, err)
+
+	asyncProducer := mocks.NewAsyncProducer(t, cfg)
+	p := newProducer(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		encoder,
+		testOrderTopic,
+		testStatusTopic,
+		asyncProducer,
+		metrics.NewRegistry(),
+	)
+
+	return p, asyncProducer
+}
+
+func counterValue(p *Producer, name, topic string) int64 {
+	return p.counter(name, topic).Count()
+}
+
+func TestProducerReportsResultsAndFlushesOnClose(t *testing.T) {
+	p, asyncProducer := newTestProducer(t)
+
+	asyncProducer.ExpectInputAndSucceed()
+	asyncProducer.ExpectInputAndFail(errors.New("broker unavailable"))
+
+	ctx := context.Background()
+	p.PublishOrderEvent(ctx, &models.Order{OrderUUID: uuid.New()})
+	p.PublishStatusEvent(ctx, &models.StatusStruct{OrderUUID: uuid.New(), Status: models.OrderStatusPaid})
+
+	// Close возвращается только после того, как прочитаны результаты обоих сообщений
+	require.NoError(t, p.Close())
+
+	require.Equal(t, int64(1), counterValue(p, metricSent, testOrderTopic))
+	require.Equal(t, int64(1), counterValue(p, metricFailed, testStatusTopic))
+}
+
+func TestProducerDropsAfterClose(t *testing.T) {
+	p, _ := newTestProducer(t)
+
+	require.NoError(t, p.Close())
+	require.NoError(t, p.Close())
+
+	p.PublishStatusEvent(context.Background(), &models.StatusStruct{OrderUUID: uuid.New()})
+
+	require.Equal(t, int64(1), counterValue(p, metricDropped, testStatusTopic))
+}
+
+func TestMessageKey(t *testing.T) {
+	orderUUID := uuid.New().String()
+
+	require.Equal(t, orderUUID, messageKey(&sarama.ProducerMessage{Key: sarama.StringEncoder(orderUUID)}))
+	require.Empty(t, messageKey(&sarama.ProducerMessage{}))
+	require.Empty(t, messageKey(nil))
+}
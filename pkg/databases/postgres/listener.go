@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnectInterval = 100 * time.Millisecond
+	listenerMaxReconnectInterval = 10 * time.Second
+)
+
+// Listener подписывается на канал LISTEN/NOTIFY и превращает уведомления в
+// сигналы пробуждения. Уведомления схлопываются: сколько бы их ни пришло, пока
+// читатель занят, он получит один сигнал. После переподключения к базе тоже
+// отправляется сигнал, так как уведомления за время разрыва могли потеряться.
+type Listener struct {
+	listener *pq.Listener
+	wakeups  chan struct{}
+	log      *slog.Logger
+}
+
+func NewListener(log *slog.Logger, dsn string, channel string) (*Listener, error) {
+	const op = "postgres.NewListener"
+
+	l := &Listener{
+		wakeups: make(chan struct{}, 1),
+		log:     log,
+	}
+
+	l.listener = pq.NewListener(dsn, listenerMinReconnectInterval, listenerMaxReconnectInterval, l.logEvent)
+
+	if err := l.listener.Listen(channel); err != nil {
+		_ = l.listener.Close()
+		return nil, fmt.Errorf("%s: listen %s: %w", op, channel, err)
+	}
+
+	go l.forward()
+
+	return l, nil
+}
+
+// Wakeups возвращает канал сигналов о новых уведомлениях
+func (l *Listener) Wakeups() <-chan struct{} {
+	return l.wakeups
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
+
+// forward пересылает уведомления в wakeups. После переподключения pq кладёт в
+// Notify nil, он тоже превращается в сигнал.
+func (l *Listener) forward() {
+	for range l.listener.Notify {
+		select {
+		case l.wakeups <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (l *Listener) logEvent(event pq.ListenerEventType, err error) {
+	const op = "postgres.Listener"
+
+	switch event {
+	case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+		if err != nil {
+			l.log.Warn(op, slog.String("connection error", err.Error()))
+		}
+	case pq.ListenerEventReconnected:
+		l.log.Info(op, slog.String("status", "reconnected"))
+	}
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/tumbleweedd/two_services_system/order_service/internal/lib/testdb"
+)
+
+const wakeupTimeout = 5 * time.Second
+
+// newTestListener подписывается на уникальный канал, чтобы тесты не получали
+// чужих уведомлений
+func newTestListener(t *testing.T) (*Listener, string) {
+	t.Helper()
+
+	channel := "listener_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	l, err := NewListener(slog.New(slog.NewTextHandler(io.Discard, nil)), testdb.URL(t), channel)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	return l, channel
+}
+
+func requireWakeup(t *testing.T, l *Listener) {
+	t.Helper()
+
+	select {
+	case <-l.Wakeups():
+	case <-time.After(wakeupTimeout):
+		t.Fatal("no wakeup")
+	}
+}
+
+func requireNoWakeup(t *testing.T, l *Listener) {
+	t.Helper()
+
+	select {
+	case <-l.Wakeups():
+		t.Fatal("unexpected wakeup")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestListenerNotify(t *testing.T) {
+	db := testdb.New(t)
+	l, channel := newTestListener(t)
+
+	_, err := db.Exec(`SELECT pg_notify($1, '')`, channel)
+	require.NoError(t, err)
+	requireWakeup(t, l)
+
+	// уведомления, пришедшие пока читатель занят, схлопываются в один сигнал
+	for i := 0; i < 3; i++ {
+		_, err = db.Exec(`SELECT pg_notify($1, '')`, channel)
+		require.NoError(t, err)
+	}
+	requireWakeup(t, l)
+	requireNoWakeup(t, l)
+}
+
+func TestListenerReconnect(t *testing.T) {
+	db := testdb.New(t)
+	l, channel := newTestListener(t)
+
+	const terminateQuery = `
+						SELECT count(pg_terminate_backend(pid)) FROM pg_stat_activity
+							WHERE pid <> pg_backend_pid() AND query LIKE 'LISTEN %' || $1 || '%'
+					`
+
+	var terminated int
+	require.NoError(t, db.Get(&terminated, terminateQuery, channel))
+	require.Equal(t, 1, terminated)
+
+	// после переподключения приходит сигнал, так как уведомления могли потеряться
+	requireWakeup(t, l)
+
+	// подписка восстановлена на новом соединении
+	_, err := db.Exec(`SELECT pg_notify($1, '')`, channel)
+	require.NoError(t, err)
+	requireWakeup(t, l)
+}